@@ -0,0 +1,255 @@
+package golangUtil
+
+import (
+	"bufio"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInitialLimit is the concurrency an adaptiveLimiter starts with when WithInitialLimit isn't given
+	DefaultInitialLimit = 20
+	// DefaultMinLimit and DefaultMaxLimit bound the concurrency when WithLimitBounds isn't given
+	DefaultMinLimit = 1
+	DefaultMaxLimit = 1000
+)
+
+// LimitAlgorithm moves the concurrency limit of an adaptiveLimiter after every request.
+// rtt is the time the request took,inFlight the number of requests in flight when it started,itself included,
+// and dropped tells whether it failed from overload. Update is called with the lock of the limiter held
+type LimitAlgorithm interface {
+	Update(limit float64, rtt time.Duration, inFlight int64, dropped bool) float64
+}
+
+// aimdAlgorithm adds one to the limit after a good request and cuts it by backoff after a dropped or slow one
+type aimdAlgorithm struct {
+	timeout time.Duration
+	backoff float64
+}
+
+// NewAIMDAlgorithm returns the additive increase multiplicative decrease algorithm,
+// a request slower than timeout counts as dropped and the limit is cut by 10% for each
+func NewAIMDAlgorithm(timeout time.Duration) *aimdAlgorithm {
+	return &aimdAlgorithm{timeout: timeout, backoff: 0.9}
+}
+
+func (a *aimdAlgorithm) Update(limit float64, rtt time.Duration, inFlight int64, dropped bool) float64 {
+	if dropped || rtt > a.timeout {
+		return limit * a.backoff
+	}
+	// only grow while the limit is used,an idle service learns nothing about its capacity
+	if float64(inFlight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// gradientAlgorithm compares the latency of the last requests with the long term latency:
+// while they match the limit grows by its square root,when recent requests queue up it shrinks in proportion.
+// it needs no latency threshold,which suits dependencies whose normal latency isn't known
+type gradientAlgorithm struct {
+	// averages of the latency in nanoseconds over about 10 and 600 requests
+	shortRtt float64
+	longRtt  float64
+	// how much slower the recent requests may be before the limit shrinks
+	tolerance float64
+	// share of the new limit taken each update
+	smoothing float64
+}
+
+// NewGradientAlgorithm returns the gradient algorithm,it tolerates recent requests up to 1.5 times slower than usual
+func NewGradientAlgorithm() *gradientAlgorithm {
+	return &gradientAlgorithm{tolerance: 1.5, smoothing: 0.2}
+}
+
+func (g *gradientAlgorithm) Update(limit float64, rtt time.Duration, inFlight int64, dropped bool) float64 {
+	sample := float64(rtt)
+	if g.longRtt == 0 {
+		g.shortRtt, g.longRtt = sample, sample
+	}
+	g.shortRtt += (sample - g.shortRtt) * 2 / 11
+	g.longRtt += (sample - g.longRtt) * 2 / 601
+	// after a slow period the long average lags behind,let it recover faster
+	if g.longRtt > 2*g.shortRtt {
+		g.longRtt *= 0.95
+	}
+	if float64(inFlight)*2 < limit {
+		return limit
+	}
+	gradient := math.Max(0.5, math.Min(1, g.tolerance*g.longRtt/g.shortRtt))
+	if dropped {
+		gradient = 0.5
+	}
+	next := limit*gradient + math.Sqrt(limit)
+	return limit*(1-g.smoothing) + next*g.smoothing
+}
+
+// adaptiveLimiter admits requests while fewer than its limit are in flight,
+// the limit follows the capacity of the service as the algorithm reads it from the latency of the requests
+type adaptiveLimiter struct {
+	lock      sync.Mutex
+	limit     float64
+	minLimit  float64
+	maxLimit  float64
+	inFlight  int64
+	algorithm LimitAlgorithm
+	clock     Clock
+}
+
+type adaptiveLimiterOptions func(limiter *adaptiveLimiter)
+
+// WithAlgorithm sets the algorithm moving the limit,the gradient one by default
+func WithAlgorithm(algorithm LimitAlgorithm) adaptiveLimiterOptions {
+	return func(limiter *adaptiveLimiter) {
+		limiter.algorithm = algorithm
+	}
+}
+
+// WithInitialLimit sets the concurrency admitted before the first request is measured
+func WithInitialLimit(limit int64) adaptiveLimiterOptions {
+	return func(limiter *adaptiveLimiter) {
+		limiter.limit = float64(limit)
+	}
+}
+
+// WithLimitBounds keeps the limit between min and max whatever the algorithm says
+func WithLimitBounds(min, max int64) adaptiveLimiterOptions {
+	return func(limiter *adaptiveLimiter) {
+		limiter.minLimit = float64(min)
+		limiter.maxLimit = float64(max)
+	}
+}
+
+// WithAdaptiveClock sets the clock the latency of the requests is measured with
+func WithAdaptiveClock(clock Clock) adaptiveLimiterOptions {
+	return func(limiter *adaptiveLimiter) {
+		limiter.clock = clock
+	}
+}
+
+// NewAdaptiveLimiter returns a concurrency limiter starting at DefaultInitialLimit with the gradient algorithm unless options say otherwise
+func NewAdaptiveLimiter(options ...adaptiveLimiterOptions) *adaptiveLimiter {
+	result := &adaptiveLimiter{
+		limit:     DefaultInitialLimit,
+		minLimit:  DefaultMinLimit,
+		maxLimit:  DefaultMaxLimit,
+		algorithm: NewGradientAlgorithm(),
+		clock:     SystemClock,
+	}
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	result.limit = result.bound(result.limit)
+	return result
+}
+
+func (l *adaptiveLimiter) bound(limit float64) float64 {
+	return math.Max(l.minLimit, math.Min(l.maxLimit, limit))
+}
+
+// Acquire takes a slot when fewer than the limit are in flight,
+// done must then be called once when the request is over,with dropped set when it failed from overload
+func (l *adaptiveLimiter) Acquire() (done func(dropped bool), ok bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if float64(l.inFlight) >= math.Floor(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	inFlight := l.inFlight
+	start := l.clock.Now()
+	var once sync.Once
+	return func(dropped bool) {
+		once.Do(func() {
+			rtt := l.clock.Now().Sub(start)
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			l.inFlight--
+			l.limit = l.bound(l.algorithm.Update(l.limit, rtt, inFlight, dropped))
+		})
+	}, true
+}
+
+// Limit returns the concurrency admitted now
+func (l *adaptiveLimiter) Limit() int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int64(l.limit)
+}
+
+// InFlight returns the number of requests holding a slot
+func (l *adaptiveLimiter) InFlight() int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.inFlight
+}
+
+// statusWriter remembers the status code written by the handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush flushes the underlying writer,it does nothing when that one can't flush
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *statusWriter) Push(target string, options *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, options)
+	}
+	return http.ErrNotSupported
+}
+
+// RegistryAdaptiveLimiter returns a middleware admitting requests while limiter has a free slot,
+// a response with a 5xx or a 429 status counts as dropped and shrinks the limit.
+// refused requests are answered like RegistryLimiter answers them
+func RegistryAdaptiveLimiter(limiter *adaptiveLimiter, options ...rateLimitMiddlewareOptions) MiddleWare {
+	middleware := newRateLimitMiddleware(options)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			done, ok := limiter.Acquire()
+			if !ok {
+				middleware.reject(writer, request, RateLimitState{Limit: limiter.Limit()})
+				return
+			}
+			recorder := &statusWriter{ResponseWriter: writer}
+			dropped := true
+			defer func() {
+				done(dropped)
+			}()
+			next.ServeHTTP(recorder, request)
+			dropped = recorder.status >= 500 || recorder.status == http.StatusTooManyRequests
+		},
+		)
+	}
+}
+
+// RegistryAdaptiveLimiting is RegistryRateLimiting with a concurrency limit following the latency of the service
+func RegistryAdaptiveLimiting(options ...adaptiveLimiterOptions) MiddleWare {
+	return RegistryAdaptiveLimiter(NewAdaptiveLimiter(options...))
+}
@@ -0,0 +1,132 @@
+package golangUtil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAIMDAlgorithm(t *testing.T) {
+	aimd := NewAIMDAlgorithm(100 * time.Millisecond)
+	if limit := aimd.Update(10, time.Millisecond, 8, false); limit != 11 {
+		t.Fatalf("good request in a busy limiter gives %v", limit)
+	}
+	if limit := aimd.Update(10, time.Millisecond, 1, false); limit != 10 {
+		t.Fatalf("idle limiter grew to %v", limit)
+	}
+	if limit := aimd.Update(10, time.Second, 8, false); limit != 9 {
+		t.Fatalf("slow request gives %v", limit)
+	}
+	if limit := aimd.Update(10, time.Millisecond, 8, true); limit != 9 {
+		t.Fatalf("dropped request gives %v", limit)
+	}
+}
+
+func TestGradientAlgorithm(t *testing.T) {
+	gradient := NewGradientAlgorithm()
+	limit := 20.0
+	for i := 0; i < 50; i++ {
+		limit = gradient.Update(limit, 10*time.Millisecond, int64(limit), false)
+	}
+	if limit <= 40 {
+		t.Fatalf("limit %v didn't grow with steady latency", limit)
+	}
+	grown := limit
+	for i := 0; i < 50; i++ {
+		limit = gradient.Update(limit, 100*time.Millisecond, int64(limit), false)
+	}
+	if limit >= grown/2 {
+		t.Fatalf("limit %v didn't shrink when latency went from 10ms to 100ms", limit)
+	}
+}
+
+func TestAdaptiveLimiterAcquire(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithInitialLimit(2), WithLimitBounds(1, 3), WithAlgorithm(NewAIMDAlgorithm(time.Second)))
+	first, ok1 := limiter.Acquire()
+	second, ok2 := limiter.Acquire()
+	if !ok1 || !ok2 || limiter.InFlight() != 2 {
+		t.Fatal("slots under the limit refused")
+	}
+	if _, ok := limiter.Acquire(); ok {
+		t.Fatal("slot past the limit admitted")
+	}
+	first(false)
+	first(false)
+	if limiter.InFlight() != 1 || limiter.Limit() != 3 {
+		t.Fatalf("after a good request %d in flight,limit %d", limiter.InFlight(), limiter.Limit())
+	}
+	second(true)
+	if limiter.Limit() != 2 {
+		t.Fatalf("limit %d after a dropped request,want 2", limiter.Limit())
+	}
+	// the bounds hold whatever the algorithm says
+	for i := 0; i < 10; i++ {
+		done, _ := limiter.Acquire()
+		done(true)
+	}
+	if limiter.Limit() != 1 {
+		t.Fatalf("limit %d under the lower bound", limiter.Limit())
+	}
+}
+
+func TestRegistryAdaptiveLimiter(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithInitialLimit(1), WithLimitBounds(1, 1))
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	handler := RegistryAdaptiveLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	var group sync.WaitGroup
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-entered
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("request past the concurrency got %d", recorder.Code)
+	}
+	close(release)
+	group.Wait()
+	if limiter.InFlight() != 0 {
+		t.Fatal("slot not released after the request")
+	}
+}
+
+func TestRegistryAdaptiveLimiterShedsOnErrors(t *testing.T) {
+	limiter := NewAdaptiveLimiter(WithInitialLimit(10), WithAlgorithm(NewAIMDAlgorithm(time.Second)))
+	handler := RegistryAdaptiveLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if limit := limiter.Limit(); limit >= 10 {
+		t.Fatalf("limit %d didn't shrink on 503 responses", limit)
+	}
+}
+
+func TestRegistryAdaptiveLimiterFlusher(t *testing.T) {
+	limiter := NewAdaptiveLimiter()
+	handler := RegistryAdaptiveLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("writer of the handler can't flush")
+		}
+		w.Write([]byte("event"))
+		flusher.Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err != http.ErrNotSupported {
+			t.Errorf("hijack of a recorder gives %v", err)
+		}
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if !recorder.Flushed || recorder.Body.String() != "event" {
+		t.Fatalf("flush didn't reach the recorder,flushed %v body %q", recorder.Flushed, recorder.Body.String())
+	}
+}
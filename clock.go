@@ -0,0 +1,124 @@
+package golangUtil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the limiters,tests swap the real clock for a fake one to control time exactly
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer sending the time on its channel once d is over
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing,it reports whether the timer was still pending
+	Stop() bool
+}
+
+// SystemClock is the real clock,the default of every limiter
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// fakeClock only moves when told to,its timers fire when Advance or Set passes their deadline
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewFakeClock returns a clock stopped at start
+func NewFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start, timers: make(map[*fakeTimer]struct{})}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	timer := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- c.now
+	} else {
+		c.timers[timer] = struct{}{}
+	}
+	return timer
+}
+
+// Advance moves the clock forward by d and fires the timers it passes
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to now,which may be in the past,and fires the timers it passes
+func (c *fakeClock) Set(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.set(now)
+}
+
+func (c *fakeClock) set(now time.Time) {
+	c.now = now
+	for timer := range c.timers {
+		if !timer.deadline.After(now) {
+			timer.c <- now
+			delete(c.timers, timer)
+		}
+	}
+}
+
+// Timers returns the number of timers waiting,a test can wait for a goroutine to block on the clock before advancing it
+func (c *fakeClock) Timers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.timers)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	_, pending := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return pending
+}
@@ -0,0 +1,51 @@
+package golangUtil
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitForTimers blocks until n timers wait on clock,so that advancing it wakes the goroutines under test
+func waitForTimers(clock *fakeClock, n int) {
+	for clock.Timers() < n {
+		runtime.Gosched()
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clock := NewFakeClock(start)
+	early := clock.NewTimer(time.Second)
+	late := clock.NewTimer(time.Minute)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("Stop of a pending timer")
+	}
+	if clock.Timers() != 2 {
+		t.Fatalf("%d timers pending", clock.Timers())
+	}
+	clock.Advance(time.Second)
+	select {
+	case now := <-early.C():
+		if !now.Equal(start.Add(time.Second)) {
+			t.Fatalf("timer fired at %v", now)
+		}
+	default:
+		t.Fatal("timer didn't fire at its deadline")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("timer fired before its deadline")
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	clock.Set(start.Add(time.Hour))
+	if _, ok := <-late.C(); !ok || clock.Timers() != 0 || !clock.Now().Equal(start.Add(time.Hour)) {
+		t.Fatal("Set didn't fire the timers it passed")
+	}
+	if _, ok := <-clock.NewTimer(0).C(); !ok {
+		t.Fatal("timer of 0 didn't fire at once")
+	}
+}
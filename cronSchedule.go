@@ -0,0 +1,190 @@
+package golangUtil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule gives the runs of a recurring task
+type Schedule interface {
+	// Next returns the first run strictly after t,the zero time when there is none
+	Next(t time.Time) time.Time
+}
+
+// everySchedule runs every interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Every returns a schedule running every interval,counted from the end of the previous run
+func Every(interval time.Duration) *everySchedule {
+	if interval <= 0 {
+		panic(any("schedule needs a positive interval"))
+	}
+	return &everySchedule{interval: interval}
+}
+
+func (e *everySchedule) Next(t time.Time) time.Time {
+	return t.Add(e.interval)
+}
+
+// cronSchedule is a cron expression,each field is a bit set of the values it matches
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// a day matches when either the day of month or the day of week does if both are restricted,like cron does.
+	// a field starting with * isn't restricted,so */2 steps through the days it keeps
+	domAny, dowAny bool
+	location       *time.Location
+}
+
+// cronField is the range of one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression of 5 fields: minute,hour,day of month,month and day of week (0 or 7 is Sunday).
+// a field is *,a value,a range a-b or a list of them separated by commas,each optionally followed by /step,
+// e.g. "0 2 * * *" runs at 02:00 and "*/15 9-17 * * 1-5" every quarter hour of the working hours.
+// @yearly,@monthly,@weekly,@daily and @hourly are accepted too. the times are in the local time zone
+func ParseCron(spec string) (*cronSchedule, error) {
+	if shortcut, ok := cronShortcuts[strings.TrimSpace(spec)]; ok {
+		spec = shortcut
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: want %d fields,got %d", spec, len(cronFields), len(fields))
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %v", spec, err)
+		}
+		sets[i] = set
+	}
+	result := &cronSchedule{
+		minute:   sets[0],
+		hour:     sets[1],
+		dom:      sets[2],
+		month:    sets[3],
+		dow:      sets[4],
+		domAny:   strings.HasPrefix(fields[2], "*"),
+		dowAny:   strings.HasPrefix(fields[4], "*"),
+		location: time.Local,
+	}
+	// 7 is Sunday too
+	if result.dow&(1<<7) != 0 {
+		result.dow |= 1
+	}
+	return result, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.IndexByte(item, '/'); slash >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[slash+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("%s: bad step in %q", bounds.name, item)
+			}
+			item = item[:slash]
+		}
+		low, high := bounds.min, bounds.max
+		if item != "*" {
+			parts := strings.SplitN(item, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(parts[0]); err != nil {
+				return 0, fmt.Errorf("%s: bad value %q", bounds.name, item)
+			}
+			high = low
+			if len(parts) == 2 {
+				if high, err = strconv.Atoi(parts[1]); err != nil {
+					return 0, fmt.Errorf("%s: bad value %q", bounds.name, item)
+				}
+			} else if step > 1 {
+				// a/step runs from a to the end of the range
+				high = bounds.max
+			}
+		}
+		if low < bounds.min || high > bounds.max || low > high {
+			return 0, fmt.Errorf("%s: %q out of %d-%d", bounds.name, item, bounds.min, bounds.max)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// In returns the schedule evaluated in location instead of the local time zone
+func (c *cronSchedule) In(location *time.Location) *cronSchedule {
+	result := *c
+	result.location = location
+	return &result
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first run after t,on a fall back the repeated hour runs once:
+// a time whose wall clock isn't after the one of t is skipped
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.location)
+	after := wallClock(t)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// every combination repeats within a few years,past that the expression never matches,e.g. 30 February
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 || !wallClock(t).After(after) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// wallClock returns the time t reads on a clock of its location,as a UTC time so that it compares across offsets
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,62 @@
+package golangUtil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(value string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", value, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	cases := []struct {
+		spec, from, want string
+	}{
+		{"0 2 * * *", "2024-03-10 01:59", "2024-03-10 02:00"},
+		{"0 2 * * *", "2024-03-10 02:00", "2024-03-11 02:00"},
+		{"*/15 9-17 * * 1-5", "2024-03-08 17:50", "2024-03-11 09:00"},
+		{"30 8 1,15 * *", "2024-02-15 09:00", "2024-03-01 08:30"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"0 12 * * 7", "2024-03-11 00:00", "2024-03-17 12:00"},
+		// both day fields restricted:either matches
+		{"0 0 13 * 5", "2024-03-01 12:00", "2024-03-08 00:00"},
+		// a field starting with * isn't restricted:only Mondays
+		{"0 0 */1 * 1", "2024-03-12 00:00", "2024-03-18 00:00"},
+		{"0 0 */2 * 1", "2024-03-12 00:00", "2024-03-25 00:00"},
+		{"@hourly", "2024-03-10 01:20", "2024-03-10 02:00"},
+		{"5/20 * * * *", "2024-03-10 01:46", "2024-03-10 02:05"},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCron(c.spec)
+		if err != nil {
+			t.Fatalf("%q: %v", c.spec, err)
+		}
+		if next := schedule.In(time.UTC).Next(at(c.from)); !next.Equal(at(c.want)) {
+			t.Fatalf("%q after %s gives %s,want %s", c.spec, c.from, next, c.want)
+		}
+	}
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Fatalf("%q parsed", spec)
+		}
+	}
+	// on the fall back of New York 1:30 happens twice,the job runs at the first one only
+	if newYork, err := time.LoadLocation("America/New_York"); err == nil {
+		schedule, _ := ParseCron("30 1 * * *")
+		first := schedule.In(newYork).Next(time.Date(2024, 11, 3, 0, 0, 0, 0, newYork))
+		if want := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC); !first.Equal(want) {
+			t.Fatalf("first run of the fall back day %s,want %s", first, want)
+		}
+		if next, want := schedule.In(newYork).Next(first), time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC); !next.Equal(want) {
+			t.Fatalf("run after %s is %s,want %s", first, next, want)
+		}
+	}
+	never, _ := ParseCron("0 0 30 2 *")
+	if next := never.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("30 February runs at %s", next)
+	}
+}
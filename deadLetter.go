@@ -0,0 +1,203 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxAttemptHistory bounds the attempts a task remembers,a task retried forever keeps the last ones
+const maxAttemptHistory = 16
+
+// Attempt is one run of a task
+type Attempt struct {
+	Start    time.Time
+	Duration time.Duration
+	// Err is nil for the attempt that succeeded
+	Err error
+}
+
+// TaskOutcome describes a task when one of its hooks is called
+type TaskOutcome struct {
+	// ID is given by the queue in the order the tasks were added
+	ID       uint64
+	Name     string
+	Priority uint8
+	// Attempts is the number of runs so far,History the last maxAttemptHistory of them
+	Attempts int
+	History  []Attempt
+	// Err is the error of the last run,nil when the task completed
+	Err error
+}
+
+// TaskHooks are called from the worker running the task,so they should return quickly.
+// OnFailure is called after every failed attempt,OnComplete and OnGiveUp once at the end of the task
+type TaskHooks struct {
+	OnComplete func(outcome TaskOutcome)
+	OnFailure  func(outcome TaskOutcome)
+	OnGiveUp   func(outcome TaskOutcome)
+}
+
+// WithHooks sets the hooks called for every task of the queue,before the hooks of the task itself
+func WithHooks(hooks TaskHooks) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.hooks = hooks
+	}
+}
+
+// WithDeadLetterSink sets where the tasks given up by their retry policy go,they are dropped without one
+func WithDeadLetterSink(sink DeadLetterSink) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.deadLetters = sink
+	}
+}
+
+// WithTaskHooks sets hooks for this task only,a durable task gets them from WithHandler
+func WithTaskHooks(hooks TaskHooks) taskOptions {
+	return func(task *task) {
+		task.hooks = hooks
+		task.notLoggable = true
+	}
+}
+
+// WithTaskName names the task in its outcomes and dead letters
+func WithTaskName(name string) taskOptions {
+	return func(task *task) {
+		task.name = name
+	}
+}
+
+// DeadLetter is a task given up by its retry policy,with what is needed to run it again
+type DeadLetter struct {
+	TaskOutcome
+	Exec   func(ctx context.Context) error
+	Policy RetryPolicy
+	// Handler and Payload are set for a durable task
+	Handler  string
+	Payload  []byte
+	FailedAt time.Time
+	// the options the task was added with,e.g. its hooks,a redrive applies them again
+	options []taskOptions
+	// the log holding a durable letter until it is released
+	wal *retryWAL
+}
+
+// Release drops a durable letter from the log of its queue,so it isn't handed to the sink again when the queue is reopened.
+// a DeadLetterSink calls it once it no longer keeps the letter,it does nothing for a task that isn't durable
+func (letter DeadLetter) Release() {
+	if letter.wal != nil {
+		letter.wal.fail(letter.wal.release(letter.ID, letter.Handler))
+	}
+}
+
+// DeadLetterSink receives the tasks given up by their retry policy,e.g. to store them or raise an alert.
+// it is called from the worker that gave the task up,and for the durable letters not released yet when the queue is reopened
+type DeadLetterSink interface {
+	DeadLetter(letter DeadLetter)
+}
+
+// ErrDeadLetterNotFound is returned by Redrive for an id the dead letter queue doesn't hold
+var ErrDeadLetterNotFound = errors.New("dead letter queue: letter not found")
+
+// memoryDeadLetterQueue keeps the dead letters in memory for inspection and re-drive
+type memoryDeadLetterQueue struct {
+	lock     sync.Mutex
+	letters  []DeadLetter
+	capacity int
+}
+
+// NewMemoryDeadLetterQueue returns a DeadLetterSink holding up to capacity letters,
+// the oldest letter is dropped for a new one when it is full,0 means no limit
+func NewMemoryDeadLetterQueue(capacity int) *memoryDeadLetterQueue {
+	return &memoryDeadLetterQueue{capacity: capacity}
+}
+
+func (d *memoryDeadLetterQueue) DeadLetter(letter DeadLetter) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.capacity > 0 && len(d.letters) >= d.capacity {
+		dropped := len(d.letters) - d.capacity + 1
+		for _, old := range d.letters[:dropped] {
+			old.Release()
+		}
+		d.letters = append(d.letters[:0], d.letters[dropped:]...)
+	}
+	d.letters = append(d.letters, letter)
+}
+
+// List returns the letters from the oldest
+func (d *memoryDeadLetterQueue) List() []DeadLetter {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]DeadLetter(nil), d.letters...)
+}
+
+func (d *memoryDeadLetterQueue) Len() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.letters)
+}
+
+// Remove drops the letter of the task id,it reports whether there was one
+func (d *memoryDeadLetterQueue) Remove(id uint64) bool {
+	letter, ok := d.take(id)
+	letter.Release()
+	return ok
+}
+
+func (d *memoryDeadLetterQueue) take(id uint64) (DeadLetter, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i, letter := range d.letters {
+		if letter.ID == id {
+			d.letters = append(d.letters[:i], d.letters[i+1:]...)
+			return letter, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+// Redrive adds the task of the letter id to query again with its priority,name,policy and the options it was added with,
+// it starts over with no attempts and a durable task is logged again with the options of its handler,and its letter released once it is.
+// the letter is kept when query refuses the task
+func (d *memoryDeadLetterQueue) Redrive(query *retryQueue, id uint64) error {
+	letter, ok := d.take(id)
+	if !ok {
+		return ErrDeadLetterNotFound
+	}
+	var err error
+	if letter.Handler != "" {
+		// the policy and the hooks of a durable task come from its handler
+		err = query.AddDurableTask(letter.Handler, letter.Payload, letter.Priority, WithTaskName(letter.Name))
+	} else {
+		options := append(append([]taskOptions(nil), letter.options...), WithRetryPolicy(letter.Policy), WithTaskName(letter.Name))
+		err = query.AddTaskContext(letter.Exec, letter.Priority, options...)
+	}
+	if err != nil {
+		d.DeadLetter(letter)
+		return err
+	}
+	letter.Release()
+	// a letter restored by another opening of the log is held by the log of query
+	if query.wal != nil && query.wal != letter.wal && letter.Handler != "" {
+		query.wal.fail(query.wal.release(letter.ID, letter.Handler))
+	}
+	return nil
+}
+
+// RedriveAll re-drives every letter,it returns how many were added before query refused one
+func (d *memoryDeadLetterQueue) RedriveAll(query *retryQueue) (int, error) {
+	count := 0
+	for _, letter := range d.List() {
+		err := d.Redrive(query, letter.ID)
+		if err == ErrDeadLetterNotFound {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,117 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryQueueHooks(t *testing.T) {
+	var lock sync.Mutex
+	var completed, failed, gaveUp []TaskOutcome
+	record := func(outcomes *[]TaskOutcome) func(TaskOutcome) {
+		return func(outcome TaskOutcome) {
+			lock.Lock()
+			defer lock.Unlock()
+			*outcomes = append(*outcomes, outcome)
+		}
+	}
+	dlq := NewMemoryDeadLetterQueue(10)
+	query := NewRetryQuery(time.Millisecond, WithConcurrencyNumber(2), WithDeadLetterSink(dlq),
+		WithHooks(TaskHooks{OnComplete: record(&completed), OnFailure: record(&failed), OnGiveUp: record(&gaveUp)}))
+	done := make(chan TaskOutcome, 1)
+	first := true
+	query.AddTask(func() error {
+		if first {
+			first = false
+			return errors.New("flaky")
+		}
+		return nil
+	}, HighPriority, WithTaskName("flaky"), WithTaskHooks(TaskHooks{OnComplete: func(outcome TaskOutcome) {
+		done <- outcome
+	}}))
+	query.AddTask(func() error {
+		return errors.New("down")
+	}, LowPriority, WithTaskName("broken"), WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+	go query.Run(context.Background())
+	outcome := <-done
+	if outcome.Name != "flaky" || outcome.Attempts != 2 || outcome.Err != nil || len(outcome.History) != 2 || outcome.History[0].Err == nil {
+		t.Fatalf("task hook got %+v", outcome)
+	}
+	for dlq.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	query.Shutdown(context.Background())
+	lock.Lock()
+	defer lock.Unlock()
+	if len(completed) != 1 || len(failed) != 4 || len(gaveUp) != 1 || gaveUp[0].Name != "broken" {
+		t.Fatalf("%d completed,%d failed,%d given up", len(completed), len(failed), len(gaveUp))
+	}
+	letter := dlq.List()[0]
+	if letter.Name != "broken" || letter.Attempts != 3 || letter.Err.Error() != "down" || len(letter.History) != 3 || letter.Exec == nil {
+		t.Fatalf("dead letter %+v", letter)
+	}
+}
+
+func TestMemoryDeadLetterQueueRedrive(t *testing.T) {
+	dlq := NewMemoryDeadLetterQueue(2)
+	for id := uint64(1); id <= 3; id++ {
+		dlq.DeadLetter(DeadLetter{TaskOutcome: TaskOutcome{ID: id, Priority: HighPriority}, Exec: func(ctx context.Context) error { return nil }})
+	}
+	if letters := dlq.List(); len(letters) != 2 || letters[0].ID != 2 || letters[1].ID != 3 {
+		t.Fatalf("full queue holds %+v,want the 2 newest letters", letters)
+	}
+	query := NewRetryQuery(time.Millisecond)
+	if err := dlq.Redrive(query, 1); err != ErrDeadLetterNotFound {
+		t.Fatalf("redrive of a dropped letter gives %v", err)
+	}
+	if err := dlq.Redrive(query, 2); err != nil || dlq.Len() != 1 {
+		t.Fatalf("redrive gives %v,%d letters left", err, dlq.Len())
+	}
+	pending, _ := query.Shutdown(context.Background())
+	if len(pending) != 1 || pending[0].Priority != HighPriority {
+		t.Fatalf("redriven task not queued: %+v", pending)
+	}
+	// a closed queue refuses the task and the letter stays
+	if count, err := dlq.RedriveAll(query); count != 0 || err != ErrQueueClosed || dlq.Len() != 1 {
+		t.Fatalf("redrive to a closed queue gives %d,%v,%d letters left", count, err, dlq.Len())
+	}
+	if !dlq.Remove(3) || dlq.Len() != 0 {
+		t.Fatal("letter not removed")
+	}
+}
+
+func TestRedriveKeepsTaskOptions(t *testing.T) {
+	dlq := NewMemoryDeadLetterQueue(10)
+	query := NewRetryQuery(time.Millisecond, WithDeadLetterSink(dlq))
+	gaveUp := make(chan TaskOutcome, 2)
+	query.AddTask(func() error {
+		return errors.New("down")
+	}, HighPriority, WithTaskName("webhook"), WithRetryPolicy(RetryPolicy{MaxAttempts: 2}), WithTaskHooks(TaskHooks{OnGiveUp: func(outcome TaskOutcome) {
+		gaveUp <- outcome
+	}}))
+	go query.Run(context.Background())
+	defer query.Shutdown(context.Background())
+	var outcome TaskOutcome
+	for i := 0; i < 2; i++ {
+		select {
+		case outcome = <-gaveUp:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("hook of the task not called,%d times given up", i)
+		}
+		// the dead letter is sent after the hooks
+		for dlq.Len() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if outcome.Name != "webhook" || outcome.Attempts != 2 {
+			t.Fatalf("given up %+v", outcome)
+		}
+		if i == 0 {
+			if err := dlq.Redrive(query, outcome.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotDurable is returned when a durable task is added to a queue opened without OpenDurableRetryQuery
+	ErrNotDurable = errors.New("retry queue: not durable")
+	// ErrUnknownHandler is returned for a durable task whose handler isn't registered with WithHandler
+	ErrUnknownHandler = errors.New("retry queue: unknown handler")
+	// ErrNotLoggable is returned for a durable task added with an option the log can't keep,e.g. WithRetryPolicy,
+	// such options go to WithHandler instead
+	ErrNotLoggable = errors.New("retry queue: option can't be logged")
+)
+
+// DurableHandler runs a durable task with the payload it was added with
+type DurableHandler func(ctx context.Context, payload []byte) error
+
+type durableHandler struct {
+	handler DurableHandler
+	options []taskOptions
+}
+
+// WithHandler registers handler under name for the durable tasks,options apply to every task of the handler,
+// e.g. its retry policy,since neither functions nor policies can be stored in the log
+func WithHandler(name string, handler DurableHandler, options ...taskOptions) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.handlers[name] = durableHandler{handler: handler, options: options}
+	}
+}
+
+// WithWALSegmentSize sets the size in bytes past which the log starts a new segment
+func WithWALSegmentSize(size int64) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.walSegmentSize = size
+	}
+}
+
+// WithWALMaxSegments sets the number of segments past which the log is compacted
+func WithWALMaxSegments(number int) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.walMaxSegments = number
+	}
+}
+
+// WithWALSync sets whether every record is synced to disk before it is acknowledged,true by default.
+// without it a crash of the machine,not of the process,may lose the last tasks
+func WithWALSync(sync bool) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.walSync = sync
+	}
+}
+
+// OpenDurableRetryQuery returns a retry queue whose durable tasks are logged in dir and survive restarts.
+// the tasks logged and not done yet are replayed into the queue with their attempts,the given up ones go to the
+// DeadLetterSink again until their letter is released. every handler they name must be registered with WithHandler.
+// a task running when the process stopped runs again,so handlers should be idempotent
+func OpenDurableRetryQuery(dir string, retryDelay time.Duration, options ...Option) (*retryQueue, error) {
+	query := NewRetryQuery(retryDelay, options...)
+	wal, err := openRetryWAL(dir, query.walSegmentSize, query.walMaxSegments, query.walSync)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range wal.entries() {
+		registered, ok := query.handlers[entry.handler]
+		if !ok {
+			wal.close()
+			return nil, fmt.Errorf("%w: %s", ErrUnknownHandler, entry.handler)
+		}
+		cur := query.newTask(registered.exec(entry.payload), entry.priority, registered.options)
+		// the options of the handler are applied again by a redrive
+		cur.id, cur.handler, cur.payload, cur.options = entry.id, entry.handler, entry.payload, nil
+		if entry.name != "" {
+			cur.name = entry.name
+		}
+		cur.attempts = int(entry.attempts)
+		cur.runAt, cur.firstRun = fromWALTime(entry.runAt), fromWALTime(entry.firstRun)
+		if entry.id > query.lastID {
+			query.lastID = entry.id
+		}
+		if entry.dead != nil {
+			// a given up task goes back to the sink,without one it waits in the log for the next opening
+			if query.deadLetters != nil {
+				cur.history = entry.dead.history
+				letter := cur.deadLetter(fromWALTime(entry.dead.failedAt))
+				letter.wal = wal
+				query.deadLetters.DeadLetter(letter)
+			}
+			continue
+		}
+		query.push(cur)
+	}
+	query.wal = wal
+	return query, nil
+}
+
+func (registered durableHandler) exec(payload []byte) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return registered.handler(ctx, payload)
+	}
+}
+
+// AddDurableTask logs a task running handler with payload and queues it,it returns once the task is on disk
+func (query *retryQueue) AddDurableTask(handler string, payload []byte, priority uint8, options ...taskOptions) error {
+	return query.AddDurableTaskAt(time.Time{}, handler, payload, priority, options...)
+}
+
+// AddDurableTaskAt is AddDurableTask for a task due at at.
+// only the options the log keeps are accepted,i.e. WithTaskName,the others are ErrNotLoggable
+func (query *retryQueue) AddDurableTaskAt(at time.Time, handler string, payload []byte, priority uint8, options ...taskOptions) error {
+	if query.wal == nil {
+		return ErrNotDurable
+	}
+	registered, ok := query.handlers[handler]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHandler, handler)
+	}
+	probe := &task{}
+	for i := 0; i < len(options); i++ {
+		options[i](probe)
+	}
+	if probe.notLoggable {
+		return ErrNotLoggable
+	}
+	payload = append([]byte(nil), payload...)
+	all := append(append([]taskOptions(nil), registered.options...), options...)
+	cur := query.newTask(registered.exec(payload), priority, all)
+	// the options of the handler are applied again by a redrive,only the ones of the task are kept
+	cur.runAt, cur.handler, cur.payload, cur.options = at, handler, payload, options
+	return query.add(cur)
+}
+
+// logEnqueue logs a durable task before it is queued,without the lock of the queue held
+func (query *retryQueue) logEnqueue(task *task) error {
+	if query.wal == nil || task.handler == "" {
+		return nil
+	}
+	return query.wal.enqueue(&walEntry{
+		id:       task.id,
+		handler:  task.handler,
+		name:     task.name,
+		payload:  task.payload,
+		priority: task.priority,
+		runAt:    walTime(task.runAt),
+	})
+}
+
+// logAttempt logs a failed attempt of a durable task and when it runs again
+func (query *retryQueue) logAttempt(task *task, runAt time.Time) {
+	if query.wal != nil && task.handler != "" {
+		query.wal.fail(query.wal.attempt(task.id, uint64(task.attempts), runAt, task.firstRun))
+	}
+}
+
+// logDone logs that a durable task completed or was given up with no sink,it won't be replayed
+func (query *retryQueue) logDone(task *task) {
+	if query.wal != nil && task.handler != "" {
+		query.wal.fail(query.wal.done(task.id))
+	}
+}
+
+// Err returns the last error of the log of a durable queue,
+// a task whose attempt or completion couldn't be logged is replayed as it was last logged
+func (query *retryQueue) Err() error {
+	if query.wal == nil {
+		return nil
+	}
+	return query.wal.lastErr()
+}
+
+// CompactWAL writes the tasks not done yet to a new segment of the log and removes the older segments,
+// the log also compacts itself when it has more than WithWALMaxSegments segments
+func (query *retryQueue) CompactWAL() error {
+	if query.wal == nil {
+		return ErrNotDurable
+	}
+	return query.wal.compactNow()
+}
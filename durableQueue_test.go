@@ -0,0 +1,341 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func shutdownQueue(t *testing.T, query *retryQueue) []PendingTask {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pending, err := query.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func TestDurableRetryQueueReplay(t *testing.T) {
+	dir := t.TempDir()
+	never := func(ctx context.Context, payload []byte) error { return errors.New("not yet") }
+	query, err := OpenDurableRetryQuery(dir, time.Hour, WithHandler("send", never))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = query.AddDurableTask("send", []byte("a"), HighPriority, WithTaskName("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err = query.AddDurableTaskAt(time.Now().Add(time.Hour), "send", []byte("b"), LowPriority); err != nil {
+		t.Fatal(err)
+	}
+	if err = query.AddDurableTask("mail", nil, LowPriority); !errors.Is(err, ErrUnknownHandler) {
+		t.Fatalf("task of an unknown handler gives %v", err)
+	}
+	// a policy of the task alone would be lost by the next opening
+	if err = query.AddDurableTask("send", nil, LowPriority, WithRetryPolicy(RetryPolicy{MaxAttempts: 1})); err != ErrNotLoggable {
+		t.Fatalf("task with its own policy gives %v", err)
+	}
+	if pending := shutdownQueue(t, query); len(pending) != 2 || pending[0].Handler != "send" || string(pending[0].Payload) != "a" {
+		t.Fatalf("pending tasks %+v", pending)
+	}
+
+	got := make(chan string, 2)
+	query, err = OpenDurableRetryQuery(dir, time.Hour, WithHandler("send", func(ctx context.Context, payload []byte) error {
+		got <- string(payload)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go query.Run(context.Background())
+	select {
+	case payload := <-got:
+		if payload != "a" {
+			t.Fatalf("replayed task runs with %q", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("replayed task didn't run")
+	}
+	pending := shutdownQueue(t, query)
+	if len(pending) != 1 || string(pending[0].Payload) != "b" || pending[0].RunAt.IsZero() {
+		t.Fatalf("the scheduled task isn't pending: %+v", pending)
+	}
+	if err = query.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the completed task is gone,the scheduled one is still there
+	query, err = OpenDurableRetryQuery(dir, time.Hour, WithHandler("send", never))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending = shutdownQueue(t, query); len(pending) != 1 || string(pending[0].Payload) != "b" {
+		t.Fatalf("tasks after reopening %+v", pending)
+	}
+	if _, err = OpenDurableRetryQuery(dir, time.Hour); !errors.Is(err, ErrUnknownHandler) {
+		t.Fatalf("opening without the handler gives %v", err)
+	}
+}
+
+func TestDurableRetryQueueAttempts(t *testing.T) {
+	dir := t.TempDir()
+	down := WithHandler("send", func(ctx context.Context, payload []byte) error {
+		return errors.New("down")
+	})
+	query, err := OpenDurableRetryQuery(dir, time.Hour, down, WithMaxRetryTimesPerTime(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.AddDurableTask("send", []byte("a"), HighPriority)
+	go query.Run(context.Background())
+	// the attempt is logged before the task waits for its retry
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		query.rw.Lock()
+		waiting := len(query.waiting)
+		query.rw.Unlock()
+		if waiting == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	shutdownQueue(t, query)
+
+	query, err = OpenDurableRetryQuery(dir, time.Hour, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := shutdownQueue(t, query)
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].RunAt.Before(time.Now().Add(30*time.Minute)) {
+		t.Fatalf("replayed tasks %+v", pending)
+	}
+}
+
+func TestDurableRetryQueueDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	letters := NewMemoryDeadLetterQueue(0)
+	down := WithHandler("send", func(ctx context.Context, payload []byte) error {
+		return errors.New("down")
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	query, err := OpenDurableRetryQuery(dir, time.Hour, WithDeadLetterSink(letters), down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.AddDurableTask("send", []byte("a"), HighPriority)
+	go query.Run(context.Background())
+	deadline := time.Now().Add(5 * time.Second)
+	for letters.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	shutdownQueue(t, query)
+	list := letters.List()
+	if len(list) != 1 || list[0].Handler != "send" || string(list[0].Payload) != "a" {
+		t.Fatalf("dead letters %+v", list)
+	}
+
+	query, err = OpenDurableRetryQuery(dir, time.Hour, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := shutdownQueue(t, query); len(pending) != 0 {
+		t.Fatalf("given up task replayed: %+v", pending)
+	}
+
+	// a re-driven durable task is logged again
+	query, err = OpenDurableRetryQuery(dir, time.Hour, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = letters.Redrive(query, list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	shutdownQueue(t, query)
+	query, err = OpenDurableRetryQuery(dir, time.Hour, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := shutdownQueue(t, query); len(pending) != 1 || string(pending[0].Payload) != "a" {
+		t.Fatalf("re-driven task not replayed: %+v", pending)
+	}
+}
+
+func TestDurableRetryQueueTornTail(t *testing.T) {
+	dir := t.TempDir()
+	handler := WithHandler("send", func(ctx context.Context, payload []byte) error { return nil })
+	query, err := OpenDurableRetryQuery(dir, time.Hour, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.AddDurableTask("send", []byte("a"), HighPriority)
+	query.AddDurableTask("send", []byte("b"), HighPriority)
+	shutdownQueue(t, query)
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	file, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a record cut by a crash
+	file.Write([]byte{40, 0, 0, 0, 1, 2, 3, 4, walEnqueue, 9})
+	file.Close()
+
+	query, err = OpenDurableRetryQuery(dir, time.Hour, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = query.AddDurableTask("send", []byte("c"), HighPriority); err != nil {
+		t.Fatal(err)
+	}
+	shutdownQueue(t, query)
+	query, err = OpenDurableRetryQuery(dir, time.Hour, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads := make(map[string]bool)
+	for _, task := range shutdownQueue(t, query) {
+		payloads[string(task.Payload)] = true
+	}
+	if len(payloads) != 3 || !payloads["a"] || !payloads["b"] || !payloads["c"] {
+		t.Fatalf("tasks after the torn record %v,want a,b and c", payloads)
+	}
+}
+
+func TestDurableRetryQueueCompaction(t *testing.T) {
+	dir := t.TempDir()
+	handler := WithHandler("send", func(ctx context.Context, payload []byte) error { return nil })
+	query, err := OpenDurableRetryQuery(dir, time.Hour, handler, WithWALSegmentSize(256), WithWALMaxSegments(2), WithWALSync(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go query.Run(context.Background())
+	for i := 0; i < 200; i++ {
+		if err = query.AddDurableTask("send", make([]byte, 32), HighPriority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query.AddDurableTaskAt(time.Now().Add(time.Hour), "send", []byte("later"), LowPriority)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		query.rw.Lock()
+		due := query.arena.Len()
+		query.rw.Unlock()
+		if due == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	shutdownQueue(t, query)
+	if segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log")); len(segments) > 3 {
+		t.Fatalf("%d segments left after compaction", len(segments))
+	}
+
+	query, err = OpenDurableRetryQuery(dir, time.Hour, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = query.CompactWAL(); err != nil {
+		t.Fatal(err)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log")); len(segments) != 1 {
+		t.Fatalf("%d segments after CompactWAL", len(segments))
+	}
+	pending := shutdownQueue(t, query)
+	if len(pending) != 1 || string(pending[0].Payload) != "later" {
+		t.Fatalf("tasks after compaction %+v", pending)
+	}
+	if err = NewRetryQuery(time.Hour).AddDurableTask("send", nil, LowPriority); err != ErrNotDurable {
+		t.Fatalf("durable task on a queue without log gives %v", err)
+	}
+}
+
+func TestDurableDeadLetterSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	gaveUp := make(chan TaskOutcome, 2)
+	down := WithHandler("send", func(ctx context.Context, payload []byte) error {
+		return errors.New("down")
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))
+	query, err := OpenDurableRetryQuery(dir, time.Millisecond, WithDeadLetterSink(NewMemoryDeadLetterQueue(0)), down,
+		WithHooks(TaskHooks{OnGiveUp: func(outcome TaskOutcome) { gaveUp <- outcome }}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.AddDurableTask("send", []byte("a"), HighPriority, WithTaskName("first"))
+	query.AddDurableTask("send", []byte("b"), HighPriority)
+	go query.Run(context.Background())
+	for i := 0; i < 2; i++ {
+		select {
+		case <-gaveUp:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d tasks given up", i)
+		}
+	}
+	shutdownQueue(t, query)
+
+	ran := make(chan string, 2)
+	up := WithHandler("send", func(ctx context.Context, payload []byte) error {
+		ran <- string(payload)
+		return nil
+	})
+	letters := NewMemoryDeadLetterQueue(0)
+	query, err = OpenDurableRetryQuery(dir, time.Millisecond, WithDeadLetterSink(letters), up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := letters.List()
+	if len(list) != 2 || list[0].Name != "first" || list[0].Handler != "send" || string(list[0].Payload) != "a" || string(list[1].Payload) != "b" {
+		t.Fatalf("dead letters after reopening %+v", list)
+	}
+	if list[0].Attempts != 2 || len(list[0].History) != 2 || list[0].Err == nil || list[0].Err.Error() != "down" || list[0].FailedAt.IsZero() {
+		t.Fatalf("dead letter history %+v", list[0])
+	}
+	// compacting keeps the letters
+	if err = query.CompactWAL(); err != nil {
+		t.Fatal(err)
+	}
+	if pending := shutdownQueue(t, query); len(pending) != 0 {
+		t.Fatalf("given up tasks replayed: %+v", pending)
+	}
+
+	// a redriven letter runs again as a durable task,a removed one is gone
+	letters = NewMemoryDeadLetterQueue(0)
+	query, err = OpenDurableRetryQuery(dir, time.Millisecond, WithDeadLetterSink(letters), up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list = letters.List()
+	if len(list) != 2 {
+		t.Fatalf("%d dead letters after the second reopening", len(list))
+	}
+	if err = letters.Redrive(query, list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	letters.Remove(list[1].ID)
+	if pending := shutdownQueue(t, query); len(pending) != 1 || pending[0].Handler != "send" || string(pending[0].Payload) != "a" || pending[0].Name != "first" {
+		t.Fatalf("redriven task %+v", pending)
+	}
+	letters = NewMemoryDeadLetterQueue(0)
+	query, err = OpenDurableRetryQuery(dir, time.Millisecond, WithDeadLetterSink(letters), up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letters.Len() != 0 {
+		t.Fatalf("released letters restored: %+v", letters.List())
+	}
+	go query.Run(context.Background())
+	select {
+	case payload := <-ran:
+		if payload != "a" {
+			t.Fatalf("replayed task runs with %q", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("redriven task not replayed")
+	}
+	shutdownQueue(t, query)
+	if err = query.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package golangUtil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// gcraLimiter implements the generic cell rate algorithm,it keeps a single theoretical arrival time
+// and spaces events evenly at the emission interval,allowing burst events ahead of schedule.
+// it shapes traffic more smoothly than a token bucket with the same rate and burst and holds no counters
+type gcraLimiter struct {
+	lock sync.Mutex
+	// time one event uses up,1/rate
+	interval time.Duration
+	// how far the theoretical arrival time may run ahead of now,burst*interval
+	tolerance time.Duration
+	burst     int64
+	// theoretical arrival time,when the schedule would be free again
+	tat   time.Time
+	clock Clock
+}
+
+// NewGCRALimiter returns a GCRA limiter admitting ratePerSecond events per second and up to burst ahead of schedule
+func NewGCRALimiter(ratePerSecond float64, burst int64, options ...bucketOptions) *gcraLimiter {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("GCRA needs a positive rate and burst"))
+	}
+	result := &gcraLimiter{clock: newBucketSettings(options).clock}
+	result.setLimit(ratePerSecond, burst)
+	return result
+}
+
+// SetLimit changes the rate and the burst of the running limiter,
+// the events already on the schedule are kept and spaced at the new rate
+func (l *gcraLimiter) SetLimit(ratePerSecond float64, burst int64) {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("GCRA needs a positive rate and burst"))
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	interval := l.interval
+	l.setLimit(ratePerSecond, burst)
+	if ahead := l.tat.Sub(now); ahead > 0 {
+		l.tat = now.Add(time.Duration(float64(ahead) * float64(l.interval) / float64(interval)))
+	}
+}
+
+func (l *gcraLimiter) setLimit(ratePerSecond float64, burst int64) {
+	l.interval = time.Duration(float64(time.Second) / ratePerSecond)
+	l.tolerance = time.Duration(burst) * l.interval
+	l.burst = burst
+}
+
+// schedule returns the arrival time after n more events
+func (l *gcraLimiter) schedule(now time.Time, n int64) time.Time {
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	return tat.Add(time.Duration(n) * l.interval)
+}
+
+func (l *gcraLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+func (l *gcraLimiter) AllowN(n int64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	tat := l.schedule(now, n)
+	if tat.Sub(now) > l.tolerance {
+		return false
+	}
+	l.tat = tat
+	return true
+}
+
+func (l *gcraLimiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN books n events on the schedule,they may happen once the schedule is back within the tolerance
+func (l *gcraLimiter) ReserveN(n int64) *Reservation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if n > l.burst {
+		return &Reservation{}
+	}
+	now := l.clock.Now()
+	tat := l.schedule(now, n)
+	timeToAct := tat.Add(-l.tolerance)
+	if timeToAct.Before(now) {
+		timeToAct = now
+	}
+	l.tat = tat
+	return &Reservation{ok: true, timeToAct: timeToAct, clock: l.clock, refund: func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.tat = l.tat.Add(-time.Duration(n) * l.interval)
+	}}
+}
+
+// State returns the quota of the schedule,Reset is when the schedule catches up with now
+func (l *gcraLimiter) State() RateLimitState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	ahead := l.schedule(now, 0).Sub(now)
+	state := RateLimitState{
+		Limit:     l.burst,
+		Remaining: int64((l.tolerance - ahead) / l.interval),
+		Reset:     ahead,
+	}
+	if state.Remaining <= 0 {
+		state.Remaining = 0
+		state.RetryAfter = ahead + l.interval - l.tolerance
+	}
+	return state
+}
+
+func (l *gcraLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events fit the schedule
+func (l *gcraLimiter) WaitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitReservation(ctx, l.ReserveN(n))
+}
@@ -0,0 +1,105 @@
+package golangUtil
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGCRABurst(t *testing.T) {
+	limiter := NewGCRALimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("event %d of the burst rejected", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("event past the burst admitted")
+	}
+}
+
+func TestGCRAAllowN(t *testing.T) {
+	limiter := NewGCRALimiter(1, 4)
+	if !limiter.AllowN(3) {
+		t.Fatal("AllowN(3) rejected on an idle limiter")
+	}
+	if limiter.AllowN(2) {
+		t.Fatal("AllowN(2) admitted with room for 1")
+	}
+	if !limiter.AllowN(1) {
+		t.Fatal("a rejected AllowN used the schedule")
+	}
+}
+
+func TestGCRASpacing(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewGCRALimiter(10, 2, WithBucketClock(clock))
+	limiter.AllowN(2)
+	// one event every 100ms once the burst is used
+	for i := 0; i < 3; i++ {
+		clock.Advance(99 * time.Millisecond)
+		if limiter.Allow() {
+			t.Fatalf("event %d admitted before its slot", i)
+		}
+		clock.Advance(time.Millisecond)
+		if !limiter.Allow() {
+			t.Fatalf("event %d rejected on its slot", i)
+		}
+	}
+	clock.Advance(time.Hour)
+	if !limiter.AllowN(2) || limiter.Allow() {
+		t.Fatal("burst not restored after a long idle period")
+	}
+}
+
+func TestGCRAReserve(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewGCRALimiter(20, 1, WithBucketClock(clock))
+	if r := limiter.Reserve(); r.Delay() != 0 {
+		t.Fatalf("first reservation waits %v", r.Delay())
+	}
+	r := limiter.Reserve()
+	if delay := r.Delay(); delay != 50*time.Millisecond {
+		t.Fatalf("second reservation waits %v,want 50ms", delay)
+	}
+	r.Cancel()
+	if delay := limiter.Reserve().Delay(); delay != 50*time.Millisecond {
+		t.Fatalf("reservation after a cancel waits %v,want 50ms", delay)
+	}
+	if r := limiter.ReserveN(2); r.OK() {
+		t.Fatal("reservation past the burst is OK")
+	}
+}
+
+func TestGCRAWait(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewGCRALimiter(200, 1, WithBucketClock(clock))
+	limiter.Allow()
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(context.Background())
+	}()
+	waitForTimers(clock, 1)
+	clock.Advance(5 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if state := limiter.State(); state.Remaining != 0 || state.RetryAfter != 5*time.Millisecond {
+		t.Fatalf("state after the wait = %+v", state)
+	}
+}
+
+func TestGCRASetLimit(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewGCRALimiter(1, 4, WithBucketClock(clock))
+	limiter.AllowN(2)
+	// the 2 events stay on the schedule,200ms ahead at 10/s
+	limiter.SetLimit(10, 4)
+	if state := limiter.State(); state.Remaining != 2 {
+		t.Fatalf("state after SetLimit = %+v", state)
+	}
+	limiter.SetLimit(1, 1)
+	if limiter.Allow() {
+		t.Fatal("lowered burst admitted with the schedule ahead")
+	}
+}
@@ -5,9 +5,7 @@ import (
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/gin-gonic/gin/render"
-	"golangUtil/sync"
 	"io"
-	"io/ioutil"
 	"log"
 	"math"
 	"mime/multipart"
@@ -17,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -98,6 +97,9 @@ func (c *Context) Copy() *Context {
 	cp.Params = paramCopy
 	return &cp
 }
+func (c *Context) HandlerName() string {
+	return nameOfFunction(c.handlers.Last())
+}
 func (c *Context) HandlerNames() []string {
 	hn := make([]string, 0, len(c.handlers))
 	for _, val := range c.handlers {
@@ -120,7 +122,7 @@ func (c *Context) Next() {
 		c.index++
 	}
 }
-func (c *Context) IsAbouted() bool {
+func (c *Context) IsAborted() bool {
 	return c.index >= abortIndex
 }
 
@@ -171,7 +173,7 @@ func (c *Context) Set(key string, value any) {
 func (c *Context) Get(key string) (value any, exists bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	value, exists = c.keys[key]
+	value, exists = c.Keys[key]
 	return
 }
 
@@ -182,6 +184,12 @@ func (c *Context) MustGet(key string) any {
 	panic(any("Key \"" + key + "\" does not exist"))
 }
 
+func (c *Context) GetBool(key string) (b bool) {
+	if val, ok := c.Get(key); ok && val != nil {
+		b, _ = val.(bool)
+	}
+	return
+}
 func (c *Context) GetString(key string) (s string) {
 	if val, ok := c.Get(key); ok && val != nil {
 		s, _ = val.(string)
@@ -309,7 +317,7 @@ func (c *Context) QueryMap(key string) (dicts map[string]string) {
 	return
 }
 
-//TODO how to do this
+// TODO how to do this
 func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
 	c.initQueryCache()
 	return c.get(c.queryCache, key)
@@ -338,7 +346,7 @@ func (c *Context) PostFormArray(key string) (values []string) {
 
 func (c *Context) initFormCache() {
 	if c.formCache == nil {
-		c.formCache = make(url.values)
+		c.formCache = make(url.Values)
 		req := c.Request
 		if err := req.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
 			if !errors.Is(err, http.ErrNotMultipart) {
@@ -358,6 +366,10 @@ func (c *Context) PostFormMap(key string) (dicts map[string]string) {
 	dicts, _ = c.GetPostFormMap(key)
 	return
 }
+func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
+	c.initFormCache()
+	return c.get(c.formCache, key)
+}
 func (c *Context) get(m map[string][]string, key string) (map[string]string, bool) {
 	dicts := make(map[string]string)
 	exist := false
@@ -406,12 +418,11 @@ func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error
 	}
 	defer out.Close()
 	_, err = io.Copy(out, src)
-	ioutil.TempFile()
 	return err
 }
 
 func (c *Context) Bind(obj any) error {
-	b := binding.Default(c.Request.Method, c.ContextType())
+	b := binding.Default(c.Request.Method, c.ContentType())
 	return c.MustBindWith(obj, b)
 }
 func (c *Context) BindJSON(obj any) error {
@@ -434,7 +445,7 @@ func (c *Context) BindHeader(obj any) error {
 }
 func (c *Context) BindUri(obj any) error {
 	if err := c.ShouldBindUri(obj); err != nil {
-		c.AbortWithError(http.StatusNotFound, err).SetType(ErrorTypeBind)
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
 		return err
 	}
 	return nil
@@ -447,7 +458,7 @@ func (c *Context) MustBindWith(obj any, b binding.Binding) error {
 	return nil
 }
 func (c *Context) ShouldBind(obj any) error {
-	b := binding.Default(c.Request.Method, c.ContextType())
+	b := binding.Default(c.Request.Method, c.ContentType())
 	return c.ShouldBindWith(obj, b)
 }
 func (c *Context) ShouldBindJSON(obj any) error {
@@ -511,7 +522,7 @@ func (c *Context) ClientIP() string {
 	}
 	if c.engine.AppEngine {
 		log.Println(`The AppEngine flag is going to be deprecated. Please check issues #2723 and #2739 and use 'TrustedPlatform: gin.PlatformGoogleAppEngine' instead.`)
-		if addr := c.requestHeader("X-Appengine-Remote-Addr"); addr != nil {
+		if addr := c.requestHeader("X-Appengine-Remote-Addr"); addr != "" {
 			return addr
 		}
 	}
@@ -552,7 +563,7 @@ func (c *Context) requestHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
-//TODO why to do this
+// TODO why to do this
 func bodyAllowedForStatus(status int) bool {
 	switch {
 	case status >= 100 && status <= 199:
@@ -575,7 +586,7 @@ func (c *Context) Header(key, value string) {
 	}
 	c.Writer.Header().Set(key, value)
 }
-func (c *Context) GetHeader(key string) {
+func (c *Context) GetHeader(key string) string {
 	return c.requestHeader(key)
 }
 func (c *Context) GetRawData() ([]byte, error) {
@@ -703,7 +714,7 @@ func (c *Context) SSEvent(name string, message any) {
 		Data:  message,
 	})
 }
-func (c *Context) Stream(strp func(w io.Writer) bool) bool {
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
 	w := c.Writer
 	clientGone := w.CloseNotify()
 	for {
@@ -725,7 +736,6 @@ type Negotiate struct {
 	HTMLName string
 	HTMLData any
 	JSONData any
-	JSONData any
 	XMLData  any
 	YAMLData any
 	Data     any
@@ -766,7 +776,7 @@ func (c *Context) NegotiateFormat(offered ...string) string {
 		for _, offer := range offered {
 			i := 0
 			for ; i < len(accepted) && i < len(offer); i++ {
-				if accepted[i] == "*" || offer[i] == "*" {
+				if accepted[i] == '*' || offer[i] == '*' {
 					return offer
 				}
 				if accepted[i] != offer[i] {
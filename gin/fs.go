@@ -5,7 +5,7 @@ import (
 	"os"
 )
 
-type onlyFileFS struct {
+type onlyFilesFS struct {
 	fs http.FileSystem
 }
 
@@ -19,10 +19,10 @@ func Dir(root string, listDirectory bool) http.FileSystem {
 		return fs
 
 	}
-	return &onlyFileFS{fs}
+	return &onlyFilesFS{fs}
 }
 
-func (fs onlyFileFS) Open(name string) (http.File, error) {
+func (fs onlyFilesFS) Open(name string) (http.File, error) {
 	f, err := fs.fs.Open(name)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,93 @@
+package gin
+
+import (
+	"golangUtil"
+	"net/http"
+)
+
+// KeyedLimiters hands out one limiter per key,golangUtil.NewKeyedLimiter returns one with a bounded number of keys and tiers
+type KeyedLimiters interface {
+	Limiter(key string) golangUtil.Limiter
+}
+
+// RateLimitKeyFunc tells which limiter of a KeyedRateLimit a request goes through
+type RateLimitKeyFunc func(c *Context) string
+
+// KeyByClientIP keys requests by ClientIP,which honors the trusted proxies of the engine
+func KeyByClientIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// KeyByFullPath keys requests by the route they matched,e.g. /users/:id,so every route of a group gets its own quota
+func KeyByFullPath(c *Context) string {
+	return c.FullPath()
+}
+
+// KeyByFullPathAndClientIP gives every client its own quota on every route
+func KeyByFullPathAndClientIP(c *Context) string {
+	return c.FullPath() + " " + c.ClientIP()
+}
+
+// RateLimitRejectHandler answers a request refused by the limiter,it must abort the context
+type RateLimitRejectHandler func(c *Context, state golangUtil.RateLimitState)
+
+// DefaultRateLimitRejectHandler aborts with 429 Too Many Requests,Retry-After and golangUtil.RateLimitingError in a JSON body
+func DefaultRateLimitRejectHandler(c *Context, state golangUtil.RateLimitState) {
+	golangUtil.SetRetryAfter(c.Writer.Header(), state)
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, H{"error": golangUtil.RateLimitingError})
+}
+
+type rateLimit struct {
+	key    RateLimitKeyFunc
+	reject RateLimitRejectHandler
+}
+
+type rateLimitOptions func(limit *rateLimit)
+
+// WithRateLimitKey sets the key of a KeyedRateLimit,KeyByClientIP by default
+func WithRateLimitKey(key RateLimitKeyFunc) rateLimitOptions {
+	return func(limit *rateLimit) {
+		limit.key = key
+	}
+}
+
+// WithRateLimitReject sets how refused requests are answered,DefaultRateLimitRejectHandler by default
+func WithRateLimitReject(reject RateLimitRejectHandler) rateLimitOptions {
+	return func(limit *rateLimit) {
+		limit.reject = reject
+	}
+}
+
+func newRateLimit(options []rateLimitOptions) *rateLimit {
+	result := &rateLimit{key: KeyByClientIP, reject: DefaultRateLimitRejectHandler}
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	return result
+}
+
+// serve sets the RateLimit headers like golangUtil.RegistryLimiter and aborts the request when limiter refuses it
+func (r *rateLimit) serve(c *Context, limiter golangUtil.Limiter) {
+	if allowed, state := golangUtil.AllowRequest(limiter, c.Writer.Header()); !allowed {
+		r.reject(c, state)
+	}
+}
+
+// RateLimit returns a handler admitting the requests of the routes it is given to through limiter,
+// e.g. router.POST("/login", gin.RateLimit(limiter), login) or router.Group("/api", gin.RateLimit(limiter)).
+// every request of those routes shares the quota,the rest of the engine isn't limited
+func RateLimit(limiter golangUtil.Limiter, options ...rateLimitOptions) HandlerFunc {
+	limit := newRateLimit(options)
+	return func(c *Context) {
+		limit.serve(c, limiter)
+	}
+}
+
+// KeyedRateLimit is RateLimit with one limiter per key,the client IP unless WithRateLimitKey says otherwise.
+// with KeyByFullPath a single handler on a RouterGroup gives each of its routes its own quota
+func KeyedRateLimit(limiters KeyedLimiters, options ...rateLimitOptions) HandlerFunc {
+	limit := newRateLimit(options)
+	return func(c *Context) {
+		limit.serve(c, limiters.Limiter(limit.key(c)))
+	}
+}
@@ -0,0 +1,112 @@
+package gin
+
+import (
+	"encoding/json"
+	"golangUtil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init() {
+	SetMode(TestMode)
+}
+
+func serve(engine *Engine, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	return recorder
+}
+
+func ok(c *Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := New()
+	engine.GET("/limited", RateLimit(golangUtil.NewTokenBucketLimiter(0.5, 2)), ok)
+	engine.GET("/free", ok)
+	var recorders []*httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		recorders = append(recorders, serve(engine, "/limited"))
+	}
+	first, rejected := recorders[0], recorders[2]
+	if first.Code != http.StatusOK || first.Header().Get("RateLimit-Limit") != "2" || first.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("first response %d %v", first.Code, first.Header())
+	}
+	if first.Header().Get("Retry-After") != "" {
+		t.Errorf("Retry-After on an admitted request")
+	}
+	header := rejected.Header()
+	if rejected.Code != http.StatusTooManyRequests || header.Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("rejected response %d %v", rejected.Code, header)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rejected.Body.Bytes(), &body); err != nil || body["error"] != golangUtil.RateLimitingError {
+		t.Errorf("rejected body %q,%v", rejected.Body.String(), err)
+	}
+	retry, _ := strconv.Atoi(header.Get("Retry-After"))
+	reset, _ := strconv.Atoi(header.Get("RateLimit-Reset"))
+	if retry < 1 || retry > 2 || reset < retry || reset > 4 {
+		t.Errorf("Retry-After %d,RateLimit-Reset %d", retry, reset)
+	}
+	// the limiter only guards the route it was given to
+	for i := 0; i < 3; i++ {
+		if recorder := serve(engine, "/free"); recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("unlimited route %d %v", recorder.Code, recorder.Header())
+		}
+	}
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	engine := New()
+	api := engine.Group("/api", RateLimit(golangUtil.NewTokenBucketLimiter(0.5, 2)))
+	api.GET("/users", ok)
+	api.GET("/orders", ok)
+	// the routes of the group share one quota
+	codes := []int{serve(engine, "/api/users").Code, serve(engine, "/api/orders").Code, serve(engine, "/api/users").Code}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("group codes %v", codes)
+	}
+}
+
+func TestKeyedRateLimitByFullPath(t *testing.T) {
+	limiters := golangUtil.NewKeyedLimiter(golangUtil.WithKeyLimiter(func() golangUtil.Limiter {
+		return golangUtil.NewSlideWindowsLimiter(golangUtil.WithWindowsSize(time.Minute), golangUtil.WithMaxPassingPerWindows(1))
+	}))
+	engine := New()
+	users := engine.Group("/users", KeyedRateLimit(limiters, WithRateLimitKey(KeyByFullPath)))
+	users.GET("/:id", ok)
+	users.GET("/:id/orders", ok)
+	// every route of the group gets its own quota,whatever its params
+	if code := serve(engine, "/users/1").Code; code != http.StatusOK {
+		t.Fatalf("first request of /users/:id %d", code)
+	}
+	if code := serve(engine, "/users/1/orders").Code; code != http.StatusOK {
+		t.Fatalf("first request of /users/:id/orders %d", code)
+	}
+	if code := serve(engine, "/users/2").Code; code != http.StatusTooManyRequests {
+		t.Fatalf("second request of /users/:id %d", code)
+	}
+}
+
+func TestRateLimitReject(t *testing.T) {
+	limiter := golangUtil.NewTokenBucketLimiter(1, 1)
+	var handled bool
+	engine := New()
+	engine.GET("/", RateLimit(limiter, WithRateLimitReject(func(c *Context, state golangUtil.RateLimitState) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, H{"limit": state.Limit})
+	})), func(c *Context) {
+		handled = true
+	})
+	limiter.Allow()
+	recorder := serve(engine, "/")
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != `{"limit":1}` {
+		t.Fatalf("custom rejection %d %s", recorder.Code, recorder.Body.String())
+	}
+	if handled {
+		t.Fatalf("the handler ran after the rejection")
+	}
+}
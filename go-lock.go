@@ -1,4 +1,4 @@
-package golangUtils
+package golangUtil
 
 import (
 	"context"
@@ -0,0 +1,319 @@
+package golangUtil
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// EvictionPolicy chooses the output dropped when the cache of a jsonEncoder is full
+type EvictionPolicy int
+
+const (
+	// LRU drops the output used least recently
+	LRU EvictionPolicy = iota
+	// LFU drops the output used least often,the least recently used one among equals
+	LFU
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	default:
+		return "EvictionPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// DefaultCacheCapacity is the number of output bytes a jsonEncoder keeps when WithCacheCapacity isn't given
+const DefaultCacheCapacity int64 = 1 << 20
+
+// CacheStats is a snapshot of the counters of a jsonEncoder
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// output bytes held now,never more than Capacity
+	Bytes    int64
+	Entries  int
+	Capacity int64
+}
+
+// HitRate is the share of cacheable calls answered from the cache,0 before the first call
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Versioned is a value telling a jsonEncoder when its content changed,
+// the version must change with every modification and a value that never changes may return a constant
+type Versioned interface {
+	JsonVersion() uint64
+}
+
+// jsonEncoder marshals like Marshal and keeps the output of pointers to Versioned values it has seen,
+// a pointer marshaled again with the same version is answered from the cache without encoding.
+// The cache is keyed by the address and the type of the pointer,a new version encodes the value again.
+// Other values are encoded every time and don't show up in the stats.
+type jsonEncoder struct {
+	capacity int64
+	policy   EvictionPolicy
+
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	// eviction order,the root is the next victim
+	order cacheHeap
+	size  int64
+	// logical clock of the accesses,the recency of an entry is the tick of its last use
+	tick uint64
+
+	hits, misses, evictions uint64
+}
+
+type cacheKey struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	version uint64
+	// keeps the pointed value alive,its address can't be reused by another value while the output is cached
+	value    any
+	output   []byte
+	uses     uint64
+	lastUsed uint64
+	// position in the eviction heap
+	index int
+}
+
+type jsonEncoderOptions func(encoder *jsonEncoder)
+
+// WithCacheCapacity sets the number of output bytes the cache may hold,output bigger than that is never cached
+func WithCacheCapacity(bytes int64) jsonEncoderOptions {
+	return func(encoder *jsonEncoder) {
+		encoder.capacity = bytes
+	}
+}
+
+// WithEvictionPolicy sets the policy choosing the output dropped when the cache is full,LRU by default
+func WithEvictionPolicy(policy EvictionPolicy) jsonEncoderOptions {
+	return func(encoder *jsonEncoder) {
+		encoder.policy = policy
+	}
+}
+
+// NewJsonEncoder returns an encoder caching up to DefaultCacheCapacity bytes of output with the LRU policy unless options say otherwise
+func NewJsonEncoder(options ...jsonEncoderOptions) *jsonEncoder {
+	encoder := &jsonEncoder{
+		capacity: DefaultCacheCapacity,
+		policy:   LRU,
+		entries:  make(map[cacheKey]*cacheEntry),
+	}
+	for i := 0; i < len(options); i++ {
+		options[i](encoder)
+	}
+	encoder.order.policy = encoder.policy
+	return encoder
+}
+
+// Marshal returns the JSON encoding of value,the same bytes Marshal returns.
+// The returned slice is the caller's,changing it doesn't touch the cache.
+func (c *jsonEncoder) Marshal(value any) ([]byte, error) {
+	key, ok := cacheKeyOf(value)
+	if !ok {
+		return Marshal(value)
+	}
+	// read before encoding,a change meanwhile leaves newer output under the older version and is encoded again next time
+	version := value.(Versioned).JsonVersion()
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && entry.version == version {
+		c.hits++
+		c.touch(entry)
+		output := append([]byte(nil), entry.output...)
+		c.mu.Unlock()
+		return output, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// encode outside the lock,concurrent misses of one value encode it twice and store it once
+	output, err := Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, version, value, output)
+	return output, nil
+}
+
+// Invalidate drops the cached output of value,e.g. to free its bytes early
+func (c *jsonEncoder) Invalidate(value any) {
+	key, ok := cacheKeyOf(value)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		c.remove(entry)
+	}
+}
+
+// Reset drops every cached output,the counters are kept
+func (c *jsonEncoder) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[cacheKey]*cacheEntry)
+	c.order.entries = nil
+	c.size = 0
+}
+
+// Stats returns the current counters of the cache
+func (c *jsonEncoder) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Bytes:     c.size,
+		Entries:   len(c.entries),
+		Capacity:  c.capacity,
+	}
+}
+
+// cacheKeyOf returns the key of a non-nil pointer to a Versioned value,other values aren't cacheable
+func cacheKeyOf(value any) (cacheKey, bool) {
+	if _, ok := value.(Versioned); !ok {
+		return cacheKey{}, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return cacheKey{}, false
+	}
+	return cacheKey{ptr: v.UnsafePointer(), typ: v.Type()}, true
+}
+
+func (c *jsonEncoder) store(key cacheKey, version uint64, value any, output []byte) {
+	size := int64(len(output))
+	if size > c.capacity {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		if entry.version == version {
+			return
+		}
+		c.remove(entry)
+	}
+	// size-aware: drop as many victims as the new output needs room for
+	for c.size+size > c.capacity && len(c.order.entries) > 0 {
+		c.remove(c.order.entries[0])
+		c.evictions++
+	}
+	c.tick++
+	entry := &cacheEntry{
+		key:      key,
+		version:  version,
+		value:    value,
+		output:   append([]byte(nil), output...),
+		uses:     1,
+		lastUsed: c.tick,
+	}
+	c.entries[key] = entry
+	c.order.push(entry)
+	c.size += size
+}
+
+func (c *jsonEncoder) touch(entry *cacheEntry) {
+	c.tick++
+	entry.uses++
+	entry.lastUsed = c.tick
+	c.order.fix(entry.index)
+}
+
+func (c *jsonEncoder) remove(entry *cacheEntry) {
+	c.order.remove(entry.index)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.output))
+}
+
+// cacheHeap is a min-heap of the cached entries in eviction order,each entry knows its index so that it can be moved after an access
+type cacheHeap struct {
+	policy  EvictionPolicy
+	entries []*cacheEntry
+}
+
+func (h *cacheHeap) less(i, j int) bool {
+	a, b := h.entries[i], h.entries[j]
+	if h.policy == LFU && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.lastUsed < b.lastUsed
+}
+
+func (h *cacheHeap) swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].index = i
+	h.entries[j].index = j
+}
+
+func (h *cacheHeap) push(entry *cacheEntry) {
+	entry.index = len(h.entries)
+	h.entries = append(h.entries, entry)
+	h.up(entry.index)
+}
+
+func (h *cacheHeap) remove(i int) {
+	last := len(h.entries) - 1
+	if i != last {
+		h.swap(i, last)
+	}
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	if i != last {
+		h.fix(i)
+	}
+}
+
+func (h *cacheHeap) fix(i int) {
+	if !h.down(i) {
+		h.up(i)
+	}
+}
+
+func (h *cacheHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(i, parent) {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+// down reports whether the entry at i moved
+func (h *cacheHeap) down(i int) bool {
+	start := i
+	for {
+		smallest := i
+		if l := 2*i + 1; l < len(h.entries) && h.less(l, smallest) {
+			smallest = l
+		}
+		if r := 2*i + 2; r < len(h.entries) && h.less(r, smallest) {
+			smallest = r
+		}
+		if smallest == i {
+			return i != start
+		}
+		h.swap(i, smallest)
+		i = smallest
+	}
+}
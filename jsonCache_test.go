@@ -0,0 +1,189 @@
+package golangUtil
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+type cachedDoc struct {
+	Body    string
+	version uint64
+}
+
+func (d *cachedDoc) JsonVersion() uint64 {
+	return d.version
+}
+
+// the bench order never changes
+func (o *benchOrder) JsonVersion() uint64 {
+	return 0
+}
+
+// newCachedDocs returns documents whose output is exactly size bytes
+func newCachedDocs(n, size int) []*cachedDoc {
+	docs := make([]*cachedDoc, n)
+	for i := range docs {
+		docs[i] = &cachedDoc{Body: strings.Repeat(string(rune('a'+i%26)), size-len(`{"Body":""}`))}
+	}
+	return docs
+}
+
+// cached marshals docs in order and reports for each one whether it was answered from the cache
+func cached(encoder *jsonEncoder, docs ...*cachedDoc) []bool {
+	result := make([]bool, len(docs))
+	for i, doc := range docs {
+		before := encoder.Stats().Hits
+		if _, err := encoder.Marshal(doc); err != nil {
+			panic(err)
+		}
+		result[i] = encoder.Stats().Hits > before
+	}
+	return result
+}
+
+func TestJsonEncoderStats(t *testing.T) {
+	encoder := NewJsonEncoder(WithCacheCapacity(100))
+	doc := &cachedDoc{Body: "x"}
+	for i := 0; i < 3; i++ {
+		bytes, err := encoder.Marshal(doc)
+		if err != nil || string(bytes) != `{"Body":"x"}` {
+			t.Fatalf("got %s %v", bytes, err)
+		}
+		// the caller's copy doesn't reach the cache
+		bytes[0] = '['
+	}
+	// values that aren't pointers to Versioned values bypass the cache
+	if _, err := encoder.Marshal(cachedDoc{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encoder.Marshal(&benchAddress{}); err != nil {
+		t.Fatal(err)
+	}
+	stats := encoder.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 || stats.Bytes != int64(len(`{"Body":"x"}`)) || stats.Capacity != 100 {
+		t.Errorf("stats %+v", stats)
+	}
+	if rate := stats.HitRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("hit rate %v", rate)
+	}
+
+	doc.Body = "y"
+	encoder.Invalidate(doc)
+	if bytes, _ := encoder.Marshal(doc); string(bytes) != `{"Body":"y"}` {
+		t.Errorf("after Invalidate got %s", bytes)
+	}
+	// output bigger than the whole cache is never stored
+	big := &cachedDoc{Body: strings.Repeat("b", 200)}
+	encoder.Marshal(big)
+	encoder.Marshal(big)
+	if stats := encoder.Stats(); stats.Hits != 2 || stats.Misses != 4 || stats.Bytes > stats.Capacity {
+		t.Errorf("stats %+v", stats)
+	}
+	encoder.Reset()
+	if stats := encoder.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("after Reset %+v", stats)
+	}
+}
+
+func TestJsonEncoderMutation(t *testing.T) {
+	encoder := NewJsonEncoder()
+	doc := &cachedDoc{Body: "x"}
+	encoder.Marshal(doc)
+	doc.Body = "y"
+	doc.version++
+	if bytes, _ := encoder.Marshal(doc); string(bytes) != `{"Body":"y"}` {
+		t.Fatalf("after a mutation got %s", bytes)
+	}
+	if bytes, _ := encoder.Marshal(doc); string(bytes) != `{"Body":"y"}` {
+		t.Fatalf("new version cached %s", bytes)
+	}
+	if stats := encoder.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 1 || stats.Bytes != int64(len(`{"Body":"y"}`)) {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestJsonEncoderLRU(t *testing.T) {
+	docs := newCachedDocs(4, 20)
+	encoder := NewJsonEncoder(WithCacheCapacity(60), WithEvictionPolicy(LRU))
+	cached(encoder, docs[0], docs[1], docs[2])
+	// docs[0] becomes the most recent,docs[1] is the victim of docs[3]
+	cached(encoder, docs[0], docs[3])
+	if got := cached(encoder, docs[0], docs[2], docs[3]); got[0] != true || got[1] != true || got[2] != true {
+		t.Errorf("want 0,2,3 cached,got %v", got)
+	}
+	stats := encoder.Stats()
+	if stats.Evictions != 1 || stats.Bytes != 60 {
+		t.Errorf("stats %+v", stats)
+	}
+	if got := cached(encoder, docs[1]); got[0] {
+		t.Errorf("docs[1] should have been evicted")
+	}
+}
+
+func TestJsonEncoderLFU(t *testing.T) {
+	docs := newCachedDocs(4, 20)
+	encoder := NewJsonEncoder(WithCacheCapacity(60), WithEvictionPolicy(LFU))
+	cached(encoder, docs[0], docs[1], docs[2])
+	cached(encoder, docs[0], docs[0], docs[1], docs[2])
+	// docs[0] is used three times,docs[1] and docs[2] twice,docs[1] is older and goes
+	cached(encoder, docs[3])
+	if got := cached(encoder, docs[0], docs[2], docs[3]); got[0] != true || got[1] != true || got[2] != true {
+		t.Errorf("want 0,2,3 cached,got %v", got)
+	}
+	if stats := encoder.Stats(); stats.Evictions != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestJsonEncoderSizeAwareEviction(t *testing.T) {
+	small := newCachedDocs(5, 20)
+	encoder := NewJsonEncoder(WithCacheCapacity(100))
+	cached(encoder, small...)
+	// a 70 byte output needs room of four small ones
+	big := newCachedDocs(1, 70)[0]
+	cached(encoder, big)
+	stats := encoder.Stats()
+	if stats.Evictions != 4 || stats.Entries != 2 || stats.Bytes != 90 {
+		t.Errorf("stats %+v", stats)
+	}
+	if got := cached(encoder, small[4], big); !got[0] || !got[1] {
+		t.Errorf("want the newest small doc and the big one cached,got %v", got)
+	}
+}
+
+func TestJsonEncoderConcurrent(t *testing.T) {
+	docs := newCachedDocs(50, 40)
+	encoder := NewJsonEncoder(WithCacheCapacity(1000), WithEvictionPolicy(LFU))
+	group := sync.WaitGroup{}
+	for g := 0; g < 16; g++ {
+		group.Add(1)
+		go func(g int) {
+			defer group.Done()
+			for i := 0; i < 500; i++ {
+				doc := docs[(i*g+i)%len(docs)]
+				bytes, err := encoder.Marshal(doc)
+				if err != nil || string(bytes) != `{"Body":"`+doc.Body+`"}` {
+					t.Errorf("got %s %v", bytes, err)
+					return
+				}
+			}
+		}(g)
+	}
+	group.Wait()
+	stats := encoder.Stats()
+	if stats.Bytes > stats.Capacity || stats.Hits+stats.Misses != 16*500 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func BenchmarkJsonEncoderCachedNested(b *testing.B) {
+	order := newBenchOrder()
+	encoder := NewJsonEncoder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoder.Marshal(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package golangUtil
+
+import (
+	"math"
+	"reflect"
+	"unicode/utf8"
+)
+
+// maxExactInt is 2^53,the integers a double holds exactly
+const maxExactInt = 1 << 53
+
+// MarshalCanonical returns the canonical JSON of value as RFC 8785 (JCS) defines it,
+// the same value always gives the same bytes so the output can be hashed or signed:
+// object members are sorted by the UTF-16 code units of their names,struct fields included,
+// numbers are written the way ECMAScript writes a double,and strings are escaped minimally,without the HTML escaping of Marshal.
+// Integers beyond ±2^53 and strings with invalid UTF-8 have no canonical form and return an UnsupportedValueError,
+// a big integer can still be written as a string with the ,string tag option.
+func MarshalCanonical(value any) ([]byte, error) {
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+	e.canonical = true
+	err := e.marshal(reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.Bytes()...), nil
+}
+
+// Canonicalize rewrites the JSON document data in the canonical form of MarshalCanonical,
+// numbers are read as doubles the way RFC 8785 reads them. like MarshalCanonical it refuses what I-JSON forbids:
+// an integer beyond ±2^53 is an UnsupportedValueError and an object with a duplicate name a SyntaxError
+func Canonicalize(data []byte) ([]byte, error) {
+	var v any
+	if err := decodeInto(decodeState{data: data, useNumber: true, uniqueNames: true}, &v); err != nil {
+		return nil, err
+	}
+	return MarshalCanonical(v)
+}
+
+// appendCanonicalFloat writes the value as a double in the ECMAScript Number.prototype.toString form,-0 is written as 0
+func appendCanonicalFloat(b []byte, value reflect.Value) ([]byte, error) {
+	f := value.Float()
+	if f == 0 && !math.IsNaN(f) {
+		return append(b, '0'), nil
+	}
+	// a float32 is widened,RFC 8785 only knows doubles
+	return appendFloat(b, reflect.ValueOf(f), 64)
+}
+
+// appendCanonicalString appends s as a quoted JSON string escaping only what RFC 8785 escapes:
+// '"','\\' and the control characters,with the short forms where JSON has one,s must be valid UTF-8
+func appendCanonicalString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '\\', '"':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+		start = i + 1
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// lessUTF16 compares a and b by their UTF-16 code units,the order RFC 8785 sorts object members in.
+// it differs from the byte order of UTF-8 only where a character beyond U+FFFF meets one in U+E000..U+FFFF
+func lessUTF16(a, b string) bool {
+	for a != "" && b != "" {
+		// ASCII fast path
+		if a[0] < utf8.RuneSelf && b[0] < utf8.RuneSelf {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return utf16Units(ra) < utf16Units(rb)
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) < len(b)
+}
+
+// utf16Units returns the code units of r as one number ordered like the units themselves,
+// a surrogate pair is its high surrogate followed by its low one
+func utf16Units(r rune) uint32 {
+	if r < 0x10000 {
+		return uint32(r) << 16
+	}
+	r -= 0x10000
+	high := 0xD800 + uint32(r>>10)
+	low := 0xDC00 + uint32(r&0x3FF)
+	return high<<16 | low
+}
@@ -0,0 +1,190 @@
+package golangUtil
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// the examples of RFC 8785 section 3.2
+func TestCanonicalizeRFC8785(t *testing.T) {
+	var vectors = []struct{ input, want string }{
+		{`{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			"literals": [null, true, false]
+		}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		{`{
+			"\u20ac": "Euro Sign",
+			"\r": "Carriage Return",
+			"\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One",
+			"\ud83d\ude00": "Emoji: Grinning Face",
+			"\u0080": "Control",
+			"\u00f6": "Latin Small Letter O With Diaeresis"
+		}`, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\"," +
+			"\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"},
+		{`{"1":{"f":{"f":"hi","F":5},"\n":56.0},"10":{},"":"empty","a":{},"111":[{"e":"yes","E":"no"}],"A":{}}`,
+			`{"":"empty","1":{"\n":56,"f":{"F":5,"f":"hi"}},"10":{},"111":[{"E":"no","e":"yes"}],"A":{},"a":{}}`},
+		{`"<script>&\u2028"`, "\"<script>&\u2028\""},
+	}
+	for _, vector := range vectors {
+		got, err := Canonicalize([]byte(vector.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != vector.want {
+			t.Errorf("canonicalize %s\n got %s\nwant %s", vector.input, got, vector.want)
+		}
+	}
+}
+
+// the IEEE 754 test vectors of RFC 8785 appendix B
+func TestMarshalCanonicalNumbers(t *testing.T) {
+	var vectors = []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, vector := range vectors {
+		got, err := MarshalCanonical(math.Float64frombits(vector.bits))
+		if err != nil || string(got) != vector.want {
+			t.Errorf("%#016x: got %s %v,want %s", vector.bits, got, err, vector.want)
+		}
+	}
+	var valueErr *UnsupportedValueError
+	for _, value := range []any{math.NaN(), math.Inf(1), int64(1<<53 + 1), uint64(math.MaxUint64), "\xff"} {
+		if _, err := MarshalCanonical(value); !errors.As(err, &valueErr) {
+			t.Errorf("%v: want UnsupportedValueError,got %v", value, err)
+		}
+	}
+	if got, err := MarshalCanonical(float32(0.1)); err != nil || string(got) != "0.10000000149011612" {
+		t.Errorf("float32 is widened to a double,got %s %v", got, err)
+	}
+}
+
+type canonicalFixture struct {
+	Zeta   string            `json:"zeta"`
+	Alpha  int               `json:"alpha"`
+	Big    uint64            `json:"big,string"`
+	Nested map[string]any    `json:"nested"`
+	Temp   celsius           `json:"temp"`
+	Labels map[colour]string `json:"labels,omitempty"`
+	embeddedBase
+}
+
+func TestMarshalCanonicalStructs(t *testing.T) {
+	value := canonicalFixture{
+		Zeta:   "<z>",
+		Alpha:  -1,
+		Big:    math.MaxUint64,
+		Nested: map[string]any{"b": []any{1.0, -0.0, "x"}, "a": nil, "\u00e9": true},
+		Temp:   21.5,
+		Labels: map[colour]string{"red": "r"},
+	}
+	value.ID = 7
+	const want = `{"alpha":-1,"big":"18446744073709551615","created":"","id":7,"labels":{"RED":"r"},` +
+		`"nested":{"a":null,"b":[1,0,"x"],"é":true},"temp":{"celsius":21.5},"zeta":"<z>"}`
+	for i := 0; i < 3; i++ {
+		got, err := MarshalCanonical(&value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("got  %s\nwant %s", got, want)
+		}
+	}
+	// canonical form is stable under a round trip through the generic representation
+	got, _ := MarshalCanonical(value)
+	again, err := Canonicalize(got)
+	if err != nil || string(again) != string(got) {
+		t.Errorf("canonicalize of canonical output changed it: %s %v", again, err)
+	}
+	// the canonical mode doesn't leak into the pooled state of a later Marshal
+	if got, _ := Marshal("<"); string(got) != `"\u003c"` {
+		t.Errorf("Marshal after MarshalCanonical got %s", got)
+	}
+}
+
+func TestLessUTF16(t *testing.T) {
+	var ordered = []string{"", "\r", "1", "A", "a", "ab", "\u0080", "ö", "€", "😀", "\ufb33", "\uffff"}
+	for i := range ordered {
+		for j := range ordered {
+			if got := lessUTF16(ordered[i], ordered[j]); got != (i < j) {
+				t.Errorf("lessUTF16(%q,%q) = %v", ordered[i], ordered[j], got)
+			}
+		}
+	}
+}
+
+func TestCanonicalizeRejects(t *testing.T) {
+	var unsupported *UnsupportedValueError
+	for _, doc := range []string{`9007199254740993`, `[-9007199254740993]`, `{"id":123456789012345678901234567890}`} {
+		if _, err := Canonicalize([]byte(doc)); !errors.As(err, &unsupported) {
+			t.Errorf("canonicalize %s: want UnsupportedValueError,got %v", doc, err)
+		}
+	}
+	// the same limit as MarshalCanonical for an int64
+	if _, err := MarshalCanonical(int64(9007199254740993)); !errors.As(err, &unsupported) {
+		t.Errorf("MarshalCanonical of 2^53+1: want UnsupportedValueError,got %v", err)
+	}
+	for doc, want := range map[string]string{`9007199254740992`: `9007199254740992`, `1e30`: `1e+30`, `12345678901234567890.5`: `12345678901234567000`} {
+		got, err := Canonicalize([]byte(doc))
+		if err != nil || string(got) != want {
+			t.Errorf("canonicalize %s gives %s,%v,want %s", doc, got, err, want)
+		}
+	}
+
+	var syntaxErr *SyntaxError
+	for _, doc := range []string{`{"a":1,"a":2}`, `[{"b":{"x":1,"x":1}}]`} {
+		if _, err := Canonicalize([]byte(doc)); !errors.As(err, &syntaxErr) {
+			t.Errorf("canonicalize %s: want SyntaxError for the duplicate name,got %v", doc, err)
+		}
+	}
+	// the output of a Marshaler is read like Canonicalize reads a document
+	if got, err := MarshalCanonical(rawCanonical(`{"b":1e30,"a":12345678901234567890.5}`)); err != nil || string(got) != `{"a":12345678901234567000,"b":1e+30}` {
+		t.Errorf("canonical Marshaler output %s,%v", got, err)
+	}
+	if _, err := MarshalCanonical(rawCanonical(`{"a":1,"a":2}`)); !errors.As(err, &syntaxErr) {
+		t.Errorf("canonical Marshaler with a duplicate name: want SyntaxError,got %v", err)
+	}
+	if _, err := MarshalCanonical(rawCanonical(`9007199254740993`)); !errors.As(err, &unsupported) {
+		t.Errorf("canonical Marshaler of 2^53+1: want UnsupportedValueError,got %v", err)
+	}
+	// Unmarshal keeps accepting duplicates the way encoding/json does
+	var v map[string]int
+	if err := Unmarshal([]byte(`{"a":1,"a":2}`), &v); err != nil || v["a"] != 2 {
+		t.Errorf("unmarshal with a duplicate name gives %v,%v", v, err)
+	}
+}
+
+// rawCanonical is a Marshaler writing its own text
+type rawCanonical string
+
+func (r rawCanonical) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
@@ -0,0 +1,931 @@
+package golangUtil
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SyntaxError describes malformed JSON input and the byte offset where it was found
+type SyntaxError struct {
+	msg    string
+	Offset int64
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("json: %s at offset %d", e.msg, e.Offset)
+}
+
+// UnmarshalTypeError describes a JSON value that can't be stored in the Go value it was decoded into
+type UnmarshalTypeError struct {
+	Value  string
+	Type   reflect.Type
+	Offset int64
+	Field  string
+	// Err is the cause when the value has the right kind but bad content,e.g. invalid base64 for a []byte
+	Err error
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	msg := "json: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+	if e.Field != "" {
+		msg = "json: cannot unmarshal " + e.Value + " into Go struct field " + e.Field + " of type " + e.Type.String()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidUnmarshalError describes an invalid argument passed to Unmarshal,the argument must be a non-nil pointer
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "json: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Pointer {
+		return "json: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "json: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in the value pointed to by v.
+// Struct fields are matched with the same names Marshal writes,an exact match is preferred and a case-insensitive match is accepted,
+// unknown keys are ignored. When a JSON value doesn't fit the target type,Unmarshal keeps decoding the rest of the document and
+// returns the first UnmarshalTypeError it met.
+func Unmarshal(data []byte, v any) error {
+	return unmarshal(data, v, false)
+}
+
+func unmarshal(data []byte, v any, useNumber bool) error {
+	return decodeInto(decodeState{data: data, useNumber: useNumber}, v)
+}
+
+// decodeInto decodes the data of d into v with the options of d
+func decodeInto(d decodeState, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	d.skipWhitespace()
+	if err := d.value(rv); err != nil {
+		return err
+	}
+	d.skipWhitespace()
+	if d.off < len(d.data) {
+		return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " after top-level value")
+	}
+	return d.savedError
+}
+
+type decodeState struct {
+	data []byte
+	off  int
+	// the first type mismatch,decoding goes on so that the rest of the document still fills the target
+	savedError error
+	// struct field being decoded,used to annotate UnmarshalTypeError
+	field string
+	// numbers stored in an interface are kept as Number instead of float64
+	useNumber bool
+	// a name repeated in an object decoded into a map is a SyntaxError instead of overwriting the first value
+	uniqueNames bool
+}
+
+const errUnexpectedEnd = "unexpected end of JSON input"
+
+func (d *decodeState) syntaxError(msg string) error {
+	return &SyntaxError{msg: msg, Offset: int64(d.off)}
+}
+
+func (d *decodeState) saveError(err error) {
+	if d.savedError == nil {
+		d.savedError = err
+	}
+}
+
+func (d *decodeState) typeError(what string, t reflect.Type, offset int) {
+	d.saveError(&UnmarshalTypeError{Value: what, Type: t, Offset: int64(offset), Field: d.field})
+}
+
+func (d *decodeState) skipWhitespace() {
+	for d.off < len(d.data) {
+		switch d.data[d.off] {
+		case ' ', '\t', '\n', '\r':
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(rune(c)))
+	return "'" + s[1:len(s)-1] + "'"
+}
+
+// value decodes the JSON value at the current offset into v,an invalid v means the value is parsed and discarded
+func (d *decodeState) value(v reflect.Value) error {
+	if d.off >= len(d.data) {
+		return d.syntaxError(errUnexpectedEnd)
+	}
+	switch c := d.data[d.off]; {
+	case c == '{':
+		return d.object(v)
+	case c == '[':
+		return d.array(v)
+	case c == '"', c == 't', c == 'f', c == 'n', c == '-', c >= '0' && c <= '9':
+		return d.literal(v)
+	default:
+		return d.syntaxError("invalid character " + quoteChar(c) + " looking for beginning of value")
+	}
+}
+
+// Unmarshaler is implemented by types that decode their own JSON representation
+type Unmarshaler interface {
+	UnmarshalJSON([]byte) error
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// indirect walks down v allocating pointers as needed until it reaches a non-pointer.
+// when decodingNull is true it stops at the last pointer so that it can be set to nil.
+// it stops early at a value implementing Unmarshaler or encoding.TextUnmarshaler,
+// a named addressable value is checked through its address so pointer receivers count.
+func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	v0 := v
+	haveAddr := false
+	if v.Kind() != reflect.Pointer && v.Type().Name() != "" && v.CanAddr() {
+		haveAddr = true
+		v = v.Addr()
+	}
+	for {
+		// a non-empty interface holding a pointer is decoded into the pointed value
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Pointer && !e.IsNil() && (!decodingNull || e.Elem().Kind() == reflect.Pointer) {
+				haveAddr = false
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Pointer {
+			break
+		}
+		if decodingNull && v.CanSet() {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if !decodingNull {
+				if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+					return nil, u, reflect.Value{}
+				}
+			}
+		}
+		if haveAddr {
+			// back to the value itself,its address was only taken for the method check
+			v = v0
+			haveAddr = false
+		} else {
+			v = v.Elem()
+		}
+	}
+	return nil, nil, v
+}
+
+func (d *decodeState) object(v reflect.Value) error {
+	start := d.off
+	if v.IsValid() {
+		u, ut, pv := indirect(v, false)
+		if u != nil {
+			if err := d.value(reflect.Value{}); err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(d.data[start:d.off])
+		}
+		if ut != nil {
+			d.typeError("object", v.Type(), start)
+			return d.value(reflect.Value{})
+		}
+		v = pv
+		if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+			obj, err := d.objectInterface()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(obj))
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			switch kt := v.Type().Key(); kt.Kind() {
+			case reflect.String,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			default:
+				if !reflect.PointerTo(kt).Implements(textUnmarshalerType) {
+					d.typeError("object", v.Type(), start)
+					v = reflect.Value{}
+				}
+			}
+			if v.IsValid() && v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+		case reflect.Struct:
+		default:
+			d.typeError("object", v.Type(), start)
+			v = reflect.Value{}
+		}
+	}
+	var fields structFields
+	if v.IsValid() && v.Kind() == reflect.Struct {
+		fields = cachedTypeFields(v.Type())
+	}
+	// skip '{'
+	d.off++
+	d.skipWhitespace()
+	if d.off < len(d.data) && d.data[d.off] == '}' {
+		d.off++
+		return nil
+	}
+	for {
+		if d.off >= len(d.data) {
+			return d.syntaxError(errUnexpectedEnd)
+		}
+		if d.data[d.off] != '"' {
+			return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " looking for beginning of object key string")
+		}
+		keyStart := d.off
+		key, err := d.string()
+		if err != nil {
+			return err
+		}
+		d.skipWhitespace()
+		if d.off >= len(d.data) {
+			return d.syntaxError(errUnexpectedEnd)
+		}
+		if d.data[d.off] != ':' {
+			return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " after object key")
+		}
+		d.off++
+		d.skipWhitespace()
+
+		var subv reflect.Value
+		var mapElem reflect.Value
+		var quoted bool
+		var origField = d.field
+		if v.IsValid() {
+			switch v.Kind() {
+			case reflect.Map:
+				mapElem = reflect.New(v.Type().Elem()).Elem()
+				subv = mapElem
+			case reflect.Struct:
+				if f := fields.lookup(key); f != nil {
+					subv = fieldByIndex(v, f.index)
+					quoted = f.quoted
+					if d.field == "" {
+						d.field = f.name
+					} else {
+						d.field = d.field + "." + f.name
+					}
+				}
+			}
+		}
+		if quoted && subv.IsValid() {
+			err = d.quotedValue(subv)
+		} else {
+			err = d.value(subv)
+		}
+		if err != nil {
+			return err
+		}
+		d.field = origField
+		if mapElem.IsValid() {
+			kt := v.Type().Key()
+			var kv reflect.Value
+			switch {
+			case reflect.PointerTo(kt).Implements(textUnmarshalerType):
+				kv = reflect.New(kt)
+				if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+					d.saveError(err)
+					kv = reflect.Value{}
+				} else {
+					kv = kv.Elem()
+				}
+			case kt.Kind() == reflect.String:
+				kv = reflect.ValueOf(key).Convert(kt)
+			default:
+				kv = d.mapKey(key, kt, keyStart)
+			}
+			if kv.IsValid() {
+				if d.uniqueNames && v.MapIndex(kv).IsValid() {
+					return &SyntaxError{msg: "duplicate object name " + strconv.Quote(key), Offset: int64(keyStart)}
+				}
+				v.SetMapIndex(kv, mapElem)
+			}
+		}
+
+		d.skipWhitespace()
+		if d.off >= len(d.data) {
+			return d.syntaxError(errUnexpectedEnd)
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+			d.skipWhitespace()
+		case '}':
+			d.off++
+			return nil
+		default:
+			return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " after object key:value pair")
+		}
+	}
+}
+
+// quotedValue decodes a field with the ,string option,the JSON string holds the literal of the value
+func (d *decodeState) quotedValue(v reflect.Value) error {
+	start := d.off
+	if d.data[d.off] != '"' {
+		if d.data[d.off] == 'n' {
+			return d.literal(v)
+		}
+		if err := d.value(reflect.Value{}); err != nil {
+			return err
+		}
+		d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", v.Type()))
+		return nil
+	}
+	s, err := d.string()
+	if err != nil {
+		return err
+	}
+	inner := decodeState{data: []byte(s), field: d.field, useNumber: d.useNumber, uniqueNames: d.uniqueNames}
+	if err := inner.literalOnly(v); err != nil || inner.off != len(inner.data) {
+		d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", s, v.Type()))
+		return nil
+	}
+	if inner.savedError != nil {
+		if typeErr, ok := inner.savedError.(*UnmarshalTypeError); ok {
+			typeErr.Offset += int64(start)
+		}
+		d.saveError(inner.savedError)
+	}
+	return nil
+}
+
+// literalOnly decodes a scalar literal,composite values aren't allowed inside a ,string field
+func (d *decodeState) literalOnly(v reflect.Value) error {
+	if d.off >= len(d.data) {
+		return d.syntaxError(errUnexpectedEnd)
+	}
+	switch c := d.data[d.off]; {
+	case c == '"', c == 't', c == 'f', c == 'n', c == '-', c >= '0' && c <= '9':
+		return d.literal(v)
+	default:
+		return d.syntaxError("invalid character " + quoteChar(c) + " looking for beginning of value")
+	}
+}
+
+// mapKey converts an object key into an integer map key
+func (d *decodeState) mapKey(key string, kt reflect.Type, offset int) reflect.Value {
+	switch kt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowInt(n) {
+			d.typeError("number "+key, kt, offset+1)
+			return reflect.Value{}
+		}
+		return reflect.ValueOf(n).Convert(kt)
+	default:
+		n, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || reflect.Zero(kt).OverflowUint(n) {
+			d.typeError("number "+key, kt, offset+1)
+			return reflect.Value{}
+		}
+		return reflect.ValueOf(n).Convert(kt)
+	}
+}
+
+func (d *decodeState) array(v reflect.Value) error {
+	start := d.off
+	if v.IsValid() {
+		u, ut, pv := indirect(v, false)
+		if u != nil {
+			if err := d.value(reflect.Value{}); err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(d.data[start:d.off])
+		}
+		if ut != nil {
+			d.typeError("array", v.Type(), start)
+			return d.value(reflect.Value{})
+		}
+		v = pv
+		if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+			arr, err := d.arrayInterface()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(arr))
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+		default:
+			d.typeError("array", v.Type(), start)
+			v = reflect.Value{}
+		}
+	}
+	// skip '['
+	d.off++
+	d.skipWhitespace()
+	i := 0
+	if d.off < len(d.data) && d.data[d.off] == ']' {
+		d.off++
+	} else {
+		for {
+			var elem reflect.Value
+			if v.IsValid() {
+				if v.Kind() == reflect.Slice {
+					if i >= v.Cap() {
+						newCap := v.Cap() + v.Cap()/2
+						if newCap < 4 {
+							newCap = 4
+						}
+						grown := reflect.MakeSlice(v.Type(), v.Len(), newCap)
+						reflect.Copy(grown, v)
+						v.Set(grown)
+					}
+					if i >= v.Len() {
+						v.SetLen(i + 1)
+					}
+				}
+				if i < v.Len() {
+					elem = v.Index(i)
+					// reuse of the backing array must not leak old values into the new element
+					elem.Set(reflect.Zero(elem.Type()))
+				}
+			}
+			if err := d.value(elem); err != nil {
+				return err
+			}
+			i++
+			d.skipWhitespace()
+			if d.off >= len(d.data) {
+				return d.syntaxError(errUnexpectedEnd)
+			}
+			if d.data[d.off] == ',' {
+				d.off++
+				d.skipWhitespace()
+				continue
+			}
+			if d.data[d.off] == ']' {
+				d.off++
+				break
+			}
+			return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " after array element")
+		}
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Array {
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+		return nil
+	}
+	if i < v.Len() {
+		v.SetLen(i)
+	}
+	if i == 0 && v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	return nil
+}
+
+func (d *decodeState) literal(v reflect.Value) error {
+	start := d.off
+	c := d.data[d.off]
+	if v.IsValid() {
+		u, ut, pv := indirect(v, c == 'n')
+		if u != nil {
+			if err := d.literal(reflect.Value{}); err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(d.data[start:d.off])
+		}
+		if ut != nil {
+			if c != '"' {
+				if err := d.literal(reflect.Value{}); err != nil {
+					return err
+				}
+				kind := "number"
+				if c == 't' || c == 'f' {
+					kind = "bool"
+				}
+				d.typeError(kind, v.Type(), start)
+				return nil
+			}
+			s, err := d.string()
+			if err != nil {
+				return err
+			}
+			if err := ut.UnmarshalText([]byte(s)); err != nil {
+				d.saveError(err)
+			}
+			return nil
+		}
+		v = pv
+	}
+	switch {
+	case c == 'n':
+		if err := d.keyword("null"); err != nil {
+			return err
+		}
+		if !v.IsValid() {
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		// null is a no-op for every other kind
+		return nil
+	case c == 't' || c == 'f':
+		word := "true"
+		if c == 'f' {
+			word = "false"
+		}
+		if err := d.keyword(word); err != nil {
+			return err
+		}
+		if !v.IsValid() {
+			return nil
+		}
+		switch {
+		case v.Kind() == reflect.Bool:
+			v.SetBool(c == 't')
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(c == 't'))
+		default:
+			d.typeError("bool", v.Type(), start)
+		}
+		return nil
+	case c == '"':
+		s, err := d.string()
+		if err != nil {
+			return err
+		}
+		if !v.IsValid() {
+			return nil
+		}
+		switch {
+		case v.Kind() == reflect.String && isNumberType(v.Type()):
+			// a quoted number is accepted the way encoding/json does,as long as it is a valid literal
+			if !isValidNumber(s) {
+				d.typeError("string "+strconv.Quote(s), v.Type(), start)
+				return nil
+			}
+			v.SetString(s)
+		case v.Kind() == reflect.String:
+			v.SetString(s)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			// Marshal writes []byte as base64
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(start), Field: d.field, Err: err})
+				return nil
+			}
+			v.SetBytes(b)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(s))
+		default:
+			d.typeError("string", v.Type(), start)
+		}
+		return nil
+	default:
+		num, err := d.number()
+		if err != nil {
+			return err
+		}
+		if !v.IsValid() {
+			return nil
+		}
+		d.storeNumber(num, v, start)
+		return nil
+	}
+}
+
+func (d *decodeState) storeNumber(num string, v reflect.Value, offset int) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError("number", v.Type(), offset)
+			return
+		}
+		if d.useNumber {
+			v.Set(reflect.ValueOf(Number(num)))
+			return
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			d.typeError("number "+num, reflect.TypeOf(0.0), offset)
+			return
+		}
+		v.Set(reflect.ValueOf(f))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			d.typeError("number "+num, v.Type(), offset)
+			return
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			d.typeError("number "+num, v.Type(), offset)
+			return
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(num, v.Type().Bits())
+		if err != nil || v.OverflowFloat(f) {
+			d.typeError("number "+num, v.Type(), offset)
+			return
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		if !isNumberType(v.Type()) {
+			d.typeError("number", v.Type(), offset)
+			return
+		}
+		v.SetString(num)
+	default:
+		d.typeError("number", v.Type(), offset)
+	}
+}
+
+func (d *decodeState) keyword(word string) error {
+	for i := 0; i < len(word); i++ {
+		if d.off >= len(d.data) {
+			return d.syntaxError(errUnexpectedEnd)
+		}
+		if d.data[d.off] != word[i] {
+			return d.syntaxError("invalid character " + quoteChar(d.data[d.off]) + " in literal " + word + " (expecting " + quoteChar(word[i]) + ")")
+		}
+		d.off++
+	}
+	return nil
+}
+
+// number scans a JSON number and returns its text
+func (d *decodeState) number() (string, error) {
+	start := d.off
+	if d.data[d.off] == '-' {
+		d.off++
+	}
+	if d.off >= len(d.data) {
+		return "", d.syntaxError(errUnexpectedEnd)
+	}
+	switch c := d.data[d.off]; {
+	case c == '0':
+		d.off++
+	case c >= '1' && c <= '9':
+		for d.off < len(d.data) && d.data[d.off] >= '0' && d.data[d.off] <= '9' {
+			d.off++
+		}
+	default:
+		return "", d.syntaxError("invalid character " + quoteChar(c) + " in numeric literal")
+	}
+	if d.off < len(d.data) && d.data[d.off] == '.' {
+		d.off++
+		if err := d.digits(); err != nil {
+			return "", err
+		}
+	}
+	if d.off < len(d.data) && (d.data[d.off] == 'e' || d.data[d.off] == 'E') {
+		d.off++
+		if d.off < len(d.data) && (d.data[d.off] == '+' || d.data[d.off] == '-') {
+			d.off++
+		}
+		if err := d.digits(); err != nil {
+			return "", err
+		}
+	}
+	return string(d.data[start:d.off]), nil
+}
+
+func (d *decodeState) digits() error {
+	if d.off >= len(d.data) {
+		return d.syntaxError(errUnexpectedEnd)
+	}
+	if c := d.data[d.off]; c < '0' || c > '9' {
+		return d.syntaxError("invalid character " + quoteChar(c) + " in numeric literal")
+	}
+	for d.off < len(d.data) && d.data[d.off] >= '0' && d.data[d.off] <= '9' {
+		d.off++
+	}
+	return nil
+}
+
+// string scans a quoted JSON string and returns it unquoted
+func (d *decodeState) string() (string, error) {
+	// skip '"'
+	d.off++
+	start := d.off
+	// fast path,no escapes and plain ASCII or valid UTF-8
+	for d.off < len(d.data) {
+		c := d.data[d.off]
+		if c == '"' {
+			s := string(d.data[start:d.off])
+			d.off++
+			if utf8.ValidString(s) {
+				return s, nil
+			}
+			return strings.ToValidUTF8(s, "�"), nil
+		}
+		if c == '\\' {
+			break
+		}
+		if c < 0x20 {
+			return "", d.syntaxError("invalid character " + quoteChar(c) + " in string literal")
+		}
+		d.off++
+	}
+	sb := strings.Builder{}
+	sb.Write(d.data[start:d.off])
+	for {
+		if d.off >= len(d.data) {
+			return "", d.syntaxError(errUnexpectedEnd)
+		}
+		c := d.data[d.off]
+		switch {
+		case c == '"':
+			d.off++
+			return sb.String(), nil
+		case c == '\\':
+			d.off++
+			if d.off >= len(d.data) {
+				return "", d.syntaxError(errUnexpectedEnd)
+			}
+			switch e := d.data[d.off]; e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+				d.off++
+			case 'b':
+				sb.WriteByte('\b')
+				d.off++
+			case 'f':
+				sb.WriteByte('\f')
+				d.off++
+			case 'n':
+				sb.WriteByte('\n')
+				d.off++
+			case 'r':
+				sb.WriteByte('\r')
+				d.off++
+			case 't':
+				sb.WriteByte('\t')
+				d.off++
+			case 'u':
+				d.off++
+				r, err := d.hex4()
+				if err != nil {
+					return "", err
+				}
+				if utf16.IsSurrogate(r) {
+					// a high surrogate must be followed by an escaped low surrogate
+					if d.off+1 < len(d.data) && d.data[d.off] == '\\' && d.data[d.off+1] == 'u' {
+						save := d.off
+						d.off += 2
+						r2, err := d.hex4()
+						if err != nil {
+							return "", err
+						}
+						if dec := utf16.DecodeRune(r, r2); dec != unicode.ReplacementChar {
+							sb.WriteRune(dec)
+							break
+						}
+						d.off = save
+					}
+					r = unicode.ReplacementChar
+				}
+				sb.WriteRune(r)
+			default:
+				return "", d.syntaxError("invalid character " + quoteChar(e) + " in string escape code")
+			}
+		case c < 0x20:
+			return "", d.syntaxError("invalid character " + quoteChar(c) + " in string literal")
+		case c < utf8.RuneSelf:
+			sb.WriteByte(c)
+			d.off++
+		default:
+			r, size := utf8.DecodeRune(d.data[d.off:])
+			d.off += size
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func (d *decodeState) hex4() (rune, error) {
+	if d.off+4 > len(d.data) {
+		return 0, d.syntaxError(errUnexpectedEnd)
+	}
+	var r rune
+	for _, c := range d.data[d.off : d.off+4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c = c - '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, d.syntaxError("invalid character " + quoteChar(c) + " in \\u hexadecimal character escape")
+		}
+		r = r*16 + rune(c)
+	}
+	d.off += 4
+	return r, nil
+}
+
+// valueInterface decodes the value at the current offset into the generic any form:
+// map[string]any,[]any,string,float64,bool or nil
+func (d *decodeState) valueInterface() (any, error) {
+	if d.off >= len(d.data) {
+		return nil, d.syntaxError(errUnexpectedEnd)
+	}
+	switch c := d.data[d.off]; {
+	case c == '{':
+		return d.objectInterface()
+	case c == '[':
+		return d.arrayInterface()
+	case c == '"':
+		return d.string()
+	case c == 'n':
+		return nil, d.keyword("null")
+	case c == 't':
+		return true, d.keyword("true")
+	case c == 'f':
+		return false, d.keyword("false")
+	case c == '-' || c >= '0' && c <= '9':
+		start := d.off
+		num, err := d.number()
+		if err != nil {
+			return nil, err
+		}
+		if d.useNumber {
+			return Number(num), nil
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			d.typeError("number "+num, reflect.TypeOf(0.0), start)
+			return math.NaN(), nil
+		}
+		return f, nil
+	default:
+		return nil, d.syntaxError("invalid character " + quoteChar(c) + " looking for beginning of value")
+	}
+}
+
+func (d *decodeState) objectInterface() (map[string]any, error) {
+	m := make(map[string]any)
+	if err := d.object(reflect.ValueOf(&m).Elem()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (d *decodeState) arrayInterface() ([]any, error) {
+	arr := make([]any, 0)
+	if err := d.array(reflect.ValueOf(&arr).Elem()); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
@@ -0,0 +1,274 @@
+package golangUtil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type roundTripStruct struct {
+	Name    string
+	Enabled bool
+	Count   int64
+	Ratio   float64
+	Tags    []int
+}
+
+type taggedStruct struct {
+	ID       int64             `json:"id,string"`
+	Name     string            `json:"name"`
+	Quoted   string            `json:"quoted,string"`
+	Note     string            `json:"note,omitempty"`
+	Secret   string            `json:"-"`
+	Attrs    map[string]string `json:"attrs"`
+	Parent   *roundTripStruct  `json:"parent"`
+	internal int
+}
+
+func TestUnmarshalRoundTrip(t *testing.T) {
+	var fixtures = []any{
+		&roundTripStruct{Name: "golangUtil", Enabled: true, Count: -42, Ratio: 0.5, Tags: []int{1, 2, 3}},
+		&roundTripStruct{},
+		&taggedStruct{ID: 5577006791947779410, Name: "xiyanggou", Quoted: "xiyangValuesagou", Attrs: map[string]string{"xiyang": "xiyangValue"}},
+		&taggedStruct{Name: "xiyang", Note: "note", Parent: &roundTripStruct{Name: "parent", Count: 1}},
+	}
+	for _, value := range differentialCorpus {
+		switch value.(type) {
+		// differentialStruct holds an []int in an interface,which comes back as []any
+		case differentialInner, tagFixture:
+			fixture := reflect.New(reflect.TypeOf(value))
+			fixture.Elem().Set(reflect.ValueOf(value))
+			fixtures = append(fixtures, fixture.Interface())
+		}
+	}
+	for _, fixture := range fixtures {
+		bytes, err := Marshal(fixture)
+		if err != nil {
+			t.Fatalf("marshal %#v: %v", fixture, err)
+		}
+		result := reflect.New(reflect.TypeOf(fixture).Elem())
+		if err := Unmarshal(bytes, result.Interface()); err != nil {
+			t.Fatalf("unmarshal %s: %v", bytes, err)
+		}
+		if !reflect.DeepEqual(result.Interface(), fixture) {
+			t.Errorf("round trip of %s\n got %#v\nwant %#v", bytes, result.Interface(), fixture)
+		}
+	}
+}
+
+func TestUnmarshalMatchesEncodingJson(t *testing.T) {
+	var corpus = []string{
+		`null`, `true`, `false`, `0`, `-0.5`, `1e3`, `12345678901234567890`, `""`,
+		`"plain"`, `"esc\"aped\\\/\b\f\n\r\t"`, `"é中😀"`, `"\ud800"`, `"中文"`,
+		`[]`, `[1,"a",null,[true,{}]]`, `{}`, ` { "a" : [ 1 , 2 ] , "b" : { "c" : null } } `,
+		`{"dup":1,"dup":2}`,
+	}
+	for _, doc := range corpus {
+		var got, want any
+		if err := Unmarshal([]byte(doc), &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", doc, err)
+		}
+		if err := json.Unmarshal([]byte(doc), &want); err != nil {
+			t.Fatalf("encoding/json unmarshal %s: %v", doc, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unmarshal %s: got %#v,want %#v", doc, got, want)
+		}
+	}
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	type inner struct {
+		Value string
+	}
+	type target struct {
+		Ptr     *inner
+		Iface   any
+		Array   [2]int
+		Slice   []string
+		Map     map[string]int
+		IntKeys map[int]bool
+		Unknown int
+		Small   uint8
+	}
+	var result target
+	err := Unmarshal([]byte(`{"ptr":{"value":"v"},"Iface":[1,{"x":"y"}],"Array":[7],"Slice":["a","b"],
+		"Map":{"one":1},"IntKeys":{"-3":true},"missing":{"deep":[1,2]},"Small":255}`), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := target{
+		Ptr:     &inner{Value: "v"},
+		Iface:   []any{1.0, map[string]any{"x": "y"}},
+		Array:   [2]int{7, 0},
+		Slice:   []string{"a", "b"},
+		Map:     map[string]int{"one": 1},
+		IntKeys: map[int]bool{-3: true},
+		Small:   255,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v,want %#v", result, want)
+	}
+
+	// null resets pointers,maps and slices but leaves other kinds alone
+	if err := Unmarshal([]byte(`{"Ptr":null,"Slice":null,"Small":null}`), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Ptr != nil || result.Slice != nil || result.Small != 255 {
+		t.Errorf("null handling: %#v", result)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var syntaxCases = []string{``, `{`, `[1,]`, `{"a" 1}`, `{"a":1,}`, `tru`, `01`, `-`, `1.`, `"\x"`, `"abc`, "\"a\tb\"", `1 2`}
+	for _, doc := range syntaxCases {
+		var v any
+		var syntaxErr *SyntaxError
+		if err := Unmarshal([]byte(doc), &v); !errors.As(err, &syntaxErr) {
+			t.Errorf("unmarshal %q: want SyntaxError,got %v", doc, err)
+		}
+	}
+
+	var result struct {
+		A int
+		B string
+		C uint8
+	}
+	err := Unmarshal([]byte(`{"A":"text","B":"kept","C":256}`), &result)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "A" {
+		t.Errorf("want UnmarshalTypeError on field A,got %v", err)
+	}
+	if result.B != "kept" {
+		t.Errorf("decoding should go on after a type error,got %#v", result)
+	}
+
+	var invalid *InvalidUnmarshalError
+	if err := Unmarshal([]byte(`{}`), result); !errors.As(err, &invalid) {
+		t.Errorf("want InvalidUnmarshalError,got %v", err)
+	}
+	if err := Unmarshal([]byte(`{}`), nil); !errors.As(err, &invalid) {
+		t.Errorf("want InvalidUnmarshalError,got %v", err)
+	}
+}
+
+func TestUnmarshalTags(t *testing.T) {
+	var result taggedStruct
+	err := Unmarshal([]byte(`{"id":"12","NAME":"n","quoted":"\"q\"","Secret":"s","internal":3,"parent":null}`), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := taggedStruct{ID: 12, Name: "n", Quoted: "q"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v,want %#v", result, want)
+	}
+	if err := Unmarshal([]byte(`{"id":12}`), &result); err == nil {
+		t.Errorf("an unquoted value in a ,string field should fail")
+	}
+}
+
+func BenchmarkGolangUtilUnmarshal(b *testing.B) {
+	data := []byte(`{"Name":"golangUtil","Enabled":true,"Count":-42,"Ratio":0.5,"Tags":[1,2,3]}`)
+	for i := 0; i < b.N; i++ {
+		var result roundTripStruct
+		if err := Unmarshal(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONUnmarshal(b *testing.B) {
+	data := []byte(`{"Name":"golangUtil","Enabled":true,"Count":-42,"Ratio":0.5,"Tags":[1,2,3]}`)
+	for i := 0; i < b.N; i++ {
+		var result roundTripStruct
+		if err := json.Unmarshal(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestUnmarshalUnmarshalers(t *testing.T) {
+	const doc = `{"Created":"2001-01-02T03:04:05Z","Updated":"2024-02-29T23:59:59.123456789+01:00","Temp":{"celsius":-3.25},` +
+		`"TempPtr":{"celsius":21.5},"Level":"L4","Levels":["L1","L2"],"Colours":{"RED":1,"GREEN":2},"IP":"10.0.0.1",` +
+		`"Big":1208925819614629174706176,"Duration":1000000000,"Raw":[1,"<b>"]}`
+	var got, want marshalerFixture
+	if err := Unmarshal([]byte(doc), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(doc), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Updated.Equal(*want.Updated) || !got.Created.Equal(want.Created) {
+		t.Errorf("times %v %v,want %v %v", got.Created, got.Updated, want.Created, want.Updated)
+	}
+	got.Updated, want.Updated = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// null resets a pointer instead of calling the unmarshaler
+	got.TempPtr = new(celsius)
+	if err := Unmarshal([]byte(`{"TempPtr":null}`), &got); err != nil || got.TempPtr != nil {
+		t.Errorf("null: %v %v", got.TempPtr, err)
+	}
+	var typeErr *UnmarshalTypeError
+	if err := Unmarshal([]byte(`{"Level":4}`), &got); !errors.As(err, &typeErr) {
+		t.Errorf("number into a TextUnmarshaler: want UnmarshalTypeError,got %v", err)
+	}
+	if err := Unmarshal([]byte(`{"Created":"yesterday"}`), &got); err == nil {
+		t.Errorf("want the time.Time parse error")
+	}
+}
+
+func TestUnmarshalBase64Error(t *testing.T) {
+	var result struct {
+		Inner struct {
+			Data []byte
+		}
+		After string
+	}
+	err := Unmarshal([]byte(`{"Inner":{"Data":"not base64!"},"After":"kept"}`), &result)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "Inner.Data" || typeErr.Type != reflect.TypeOf([]byte(nil)) {
+		t.Fatalf("want UnmarshalTypeError on Inner.Data,got %v", err)
+	}
+	var corrupt base64.CorruptInputError
+	if !errors.As(err, &corrupt) {
+		t.Errorf("the base64 error isn't wrapped: %v", err)
+	}
+	if result.After != "kept" {
+		t.Errorf("decoding should go on after a bad base64 value,got %#v", result)
+	}
+}
+
+func TestUnmarshalNumberTargets(t *testing.T) {
+	var result struct {
+		Big    Number
+		Std    json.Number
+		Quoted json.Number
+		Iface  any
+	}
+	if err := Unmarshal([]byte(`{"Big":12345678901234567890,"Std":-1.5e3,"Quoted":"42","Iface":7}`), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Big != "12345678901234567890" || result.Std != "-1.5e3" || result.Quoted != "42" || result.Iface != 7.0 {
+		t.Errorf("got %#v", result)
+	}
+	out, err := Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"Big":12345678901234567890,"Std":-1.5e3,"Quoted":42,"Iface":7}` {
+		t.Errorf("round trip gives %s", out)
+	}
+
+	var typeErr *UnmarshalTypeError
+	if err := Unmarshal([]byte(`{"Std":"abc"}`), &result); !errors.As(err, &typeErr) || typeErr.Field != "Std" {
+		t.Errorf("want UnmarshalTypeError for a quoted non number,got %v", err)
+	}
+	if err := Unmarshal([]byte(`{"Big":true}`), &result); !errors.As(err, &typeErr) || typeErr.Field != "Big" {
+		t.Errorf("want UnmarshalTypeError for a bool into Number,got %v", err)
+	}
+}
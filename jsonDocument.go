@@ -0,0 +1,309 @@
+package golangUtil
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A JSON document is held as a tree of plain Go values: map[string]any for objects,[]any for arrays,
+// string,Number,bool and nil. ParseValue builds such a tree,Pointer,JSONPath and MergePatch work on it
+// and Marshal writes it back.
+
+// Number is a JSON number kept as its literal,so that integers beyond the precision of a float64 survive a round trip
+type Number string
+
+var (
+	numberType     = reflect.TypeOf(Number(""))
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// isNumberType tells the string types holding a number literal,Number and json.Number
+func isNumberType(t reflect.Type) bool {
+	return t == numberType || t == jsonNumberType
+}
+
+func (n Number) String() string { return string(n) }
+
+// Float64 returns the number as a float64
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64,it fails for fractions and out of range values
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// numberEncoder writes the literal of a Number,an empty Number is written as 0 the way encoding/json does
+func numberEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	n := value.String()
+	if n == "" {
+		n = "0"
+	}
+	if !isValidNumber(n) {
+		return &UnsupportedValueError{Value: value, Str: "invalid number literal " + strconv.Quote(n)}
+	}
+	if e.canonical {
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return &UnsupportedValueError{Value: value, Str: n + " is out of the range of a double"}
+		}
+		if !opts.quoted && !isExactNumber(n) {
+			return &UnsupportedValueError{Value: value, Str: n + " is out of the exact range of a double"}
+		}
+		if opts.quoted {
+			e.WriteByte('"')
+		}
+		e.buf, err = appendCanonicalFloat(e.buf, reflect.ValueOf(f))
+		if opts.quoted {
+			e.WriteByte('"')
+		}
+		return err
+	}
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	e.WriteString(n)
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	return nil
+}
+
+// isExactNumber tells whether the literal n keeps its value as a double,
+// only integers written without fraction or exponent may be out of the exact range
+func isExactNumber(n string) bool {
+	if strings.ContainsAny(n, ".eE") {
+		return true
+	}
+	i, err := strconv.ParseInt(n, 10, 64)
+	return err == nil && i <= maxExactInt && i >= -maxExactInt
+}
+
+// isValidNumber reports whether s is a JSON number literal
+func isValidNumber(s string) bool {
+	d := decodeState{data: []byte(s)}
+	if s == "" || s[0] != '-' && (s[0] < '0' || s[0] > '9') {
+		return false
+	}
+	if _, err := d.number(); err != nil {
+		return false
+	}
+	return d.off == len(s)
+}
+
+// ParseValue parses a JSON document into a tree of map[string]any,[]any,string,Number,bool and nil
+func ParseValue(data []byte) (any, error) {
+	var v any
+	if err := unmarshal(data, &v, true); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// PointerError describes a JSON Pointer that is malformed or doesn't fit the document
+type PointerError struct {
+	Pointer string
+	msg     string
+	err     error
+}
+
+func (e *PointerError) Error() string {
+	return "json pointer " + strconv.Quote(e.Pointer) + ": " + e.msg
+}
+
+func (e *PointerError) Unwrap() error { return e.err }
+
+// ErrPointerNotFound is wrapped by the PointerError of a pointer naming a member or an element that doesn't exist
+var ErrPointerNotFound = errors.New("not found")
+
+// Pointer is an RFC 6901 JSON Pointer,its reference tokens are kept unescaped
+type Pointer []string
+
+// ParsePointer parses a JSON Pointer such as /items/0/name,"" points to the whole document
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, &PointerError{Pointer: s, msg: "must start with '/'"}
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || token[j+1] != '0' && token[j+1] != '1') {
+				return nil, &PointerError{Pointer: s, msg: "'~' must be followed by '0' or '1'"}
+			}
+		}
+		// ~1 first,so that ~01 becomes ~1 and not /
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return Pointer(tokens), nil
+}
+
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// Get returns the value p points to in doc
+func (p Pointer) Get(doc any) (any, error) {
+	node := doc
+	for i, token := range p {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, p.notFound(i)
+			}
+			node = child
+		case []any:
+			index, err := p.index(i, len(n))
+			if err != nil {
+				return nil, err
+			}
+			if index == len(n) {
+				return nil, p.notFound(i)
+			}
+			node = n[index]
+		default:
+			return nil, p.errorAt(i, "can't be applied to a "+kindName(node))
+		}
+	}
+	return node, nil
+}
+
+// Set stores value where p points in doc and returns the root of the document.
+// Objects and arrays are modified in place,a missing last member is added and the index "-" or the length of an array appends to it,
+// the returned root only differs from doc when p is empty or appends to the root array.
+// Every container before the last token must exist.
+func (p Pointer) Set(doc any, value any) (any, error) {
+	return p.set(doc, 0, value)
+}
+
+func (p Pointer) set(node any, i int, value any) (any, error) {
+	if i == len(p) {
+		return value, nil
+	}
+	last := i == len(p)-1
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[p[i]]
+		if !ok && !last {
+			return nil, p.notFound(i)
+		}
+		child, err := p.set(child, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		n[p[i]] = child
+		return n, nil
+	case []any:
+		index, err := p.index(i, len(n))
+		if err != nil {
+			return nil, err
+		}
+		if index == len(n) {
+			if !last {
+				return nil, p.notFound(i)
+			}
+			return append(n, value), nil
+		}
+		child, err := p.set(n[index], i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	default:
+		return nil, p.errorAt(i, "can't be applied to a "+kindName(node))
+	}
+}
+
+// index parses the array index at token i,"-" is the position past the last element
+func (p Pointer) index(i int, length int) (int, error) {
+	token := p[i]
+	if token == "-" {
+		return length, nil
+	}
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, p.errorAt(i, "invalid array index")
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, p.errorAt(i, "invalid array index")
+	}
+	if index > length {
+		return 0, p.notFound(i)
+	}
+	return index, nil
+}
+
+func (p Pointer) errorAt(i int, msg string) error {
+	return &PointerError{Pointer: p.String(), msg: msg + " at " + strconv.Quote(Pointer(p[:i+1]).String())}
+}
+
+func (p Pointer) notFound(i int) error {
+	return &PointerError{Pointer: p.String(), msg: Pointer(p[:i+1]).String() + " not found", err: ErrPointerNotFound}
+}
+
+// kindName names the JSON kind of a node of a document tree
+func kindName(node any) string {
+	switch node.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// MergePatch applies an RFC 7396 merge patch to target and returns the result:
+// members of an object patch replace those of target,a null member removes one,and any other patch replaces target as a whole.
+// target isn't modified,the result shares the subtrees the patch doesn't touch.
+func MergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, _ := target.(map[string]any)
+	result := make(map[string]any, len(t)+len(p))
+	for k, v := range t {
+		result[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = MergePatch(result[k], v)
+	}
+	return result
+}
+
+// MergePatchJSON applies the merge patch document patch to the document doc and returns the patched document
+func MergePatchJSON(doc, patch []byte) ([]byte, error) {
+	target, err := ParseValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseValue(patch)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(MergePatch(target, p))
+}
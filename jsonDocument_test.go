@@ -0,0 +1,202 @@
+package golangUtil
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseValueKeepsNumbers(t *testing.T) {
+	const doc = `{"id":12345678901234567890,"price":9.90,"tags":["a",1e3],"ok":true,"none":null}`
+	v, err := ParseValue([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]any)
+	if m["id"] != Number("12345678901234567890") || m["price"] != Number("9.90") {
+		t.Errorf("numbers %#v %#v", m["id"], m["price"])
+	}
+	bytes, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":12345678901234567890,"none":null,"ok":true,"price":9.90,"tags":["a",1e3]}`; string(bytes) != want {
+		t.Errorf("got  %s\nwant %s", bytes, want)
+	}
+	if _, err := Marshal(Number("1.")); err == nil {
+		t.Errorf("want an error for an invalid Number")
+	}
+	// the id has no exact double,the canonical form refuses it like it refuses a big int64
+	var unsupported *UnsupportedValueError
+	if _, err := MarshalCanonical(v); !errors.As(err, &unsupported) {
+		t.Errorf("canonical of a big integer Number: want UnsupportedValueError,got %v", err)
+	}
+	canonical, err := MarshalCanonical(map[string]any{"price": m["price"], "tags": m["tags"]})
+	if err != nil || string(canonical) != `{"price":9.9,"tags":["a",1000]}` {
+		t.Errorf("canonical %s %v", canonical, err)
+	}
+
+	var target struct {
+		ID    Number
+		Price float64
+	}
+	if err := Unmarshal([]byte(doc), &target); err != nil || target.ID != "12345678901234567890" || target.Price != 9.9 {
+		t.Errorf("unmarshal into Number: %+v %v", target, err)
+	}
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	var streamed any
+	if err := dec.Decode(&streamed); err != nil || !reflect.DeepEqual(streamed, v) {
+		t.Errorf("UseNumber decoded %#v %v", streamed, err)
+	}
+}
+
+// the example document of RFC 6901 section 5
+const pointerDoc = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func TestPointerRFC6901(t *testing.T) {
+	doc, err := ParseValue([]byte(pointerDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors = map[string]string{
+		"":       pointerDoc,
+		"/foo":   `["bar","baz"]`,
+		"/foo/0": `"bar"`,
+		"/":      `0`,
+		"/a~1b":  `1`,
+		"/c%d":   `2`,
+		"/e^f":   `3`,
+		"/g|h":   `4`,
+		"/i\\j":  `5`,
+		"/k\"l":  `6`,
+		"/ ":     `7`,
+		"/m~0n":  `8`,
+	}
+	for pointer, want := range vectors {
+		p, err := ParsePointer(pointer)
+		if err != nil {
+			t.Fatalf("%q: %v", pointer, err)
+		}
+		if p.String() != pointer {
+			t.Errorf("%q is written back as %q", pointer, p.String())
+		}
+		got, err := p.Get(doc)
+		if err != nil {
+			t.Errorf("%q: %v", pointer, err)
+			continue
+		}
+		wantValue, _ := ParseValue([]byte(want))
+		if !reflect.DeepEqual(got, wantValue) {
+			t.Errorf("%q: got %#v,want %s", pointer, got, want)
+		}
+	}
+
+	for _, pointer := range []string{"/foo/2", "/foo/-", "/nope", "/nope/x"} {
+		if _, err := Pointer(mustPointer(t, pointer)).Get(doc); !errors.Is(err, ErrPointerNotFound) {
+			t.Errorf("%q: want ErrPointerNotFound,got %v", pointer, err)
+		}
+	}
+	var pointerErr *PointerError
+	for _, pointer := range []string{"foo", "/m~2n", "/m~"} {
+		if _, err := ParsePointer(pointer); !errors.As(err, &pointerErr) {
+			t.Errorf("%q: want PointerError,got %v", pointer, err)
+		}
+	}
+	for _, pointer := range []string{"/foo/01", "/foo/x", "/foo/0/x", "/a~1b/0"} {
+		if _, err := mustPointer(t, pointer).Get(doc); !errors.As(err, &pointerErr) || errors.Is(err, ErrPointerNotFound) {
+			t.Errorf("%q: want a PointerError other than not found,got %v", pointer, err)
+		}
+	}
+}
+
+func mustPointer(t *testing.T, s string) Pointer {
+	p, err := ParsePointer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPointerSet(t *testing.T) {
+	doc, _ := ParseValue([]byte(`{"user":{"name":"a","roles":["r1"]},"list":[]}`))
+	steps := []struct {
+		pointer string
+		value   any
+	}{
+		{"/user/name", "b"},
+		{"/user/email", "b@example.com"},
+		{"/user/roles/0", "admin"},
+		{"/user/roles/-", "ops"},
+		{"/user/roles/2", "dev"},
+		{"/list/0", map[string]any{"n": Number("1")}},
+		{"/list/0/n", Number("2")},
+	}
+	var err error
+	for _, step := range steps {
+		if doc, err = mustPointer(t, step.pointer).Set(doc, step.value); err != nil {
+			t.Fatalf("%s: %v", step.pointer, err)
+		}
+	}
+	bytes, _ := Marshal(doc)
+	if want := `{"list":[{"n":2}],"user":{"email":"b@example.com","name":"b","roles":["admin","ops","dev"]}}`; string(bytes) != want {
+		t.Errorf("got  %s\nwant %s", bytes, want)
+	}
+	for _, pointer := range []string{"/missing/x", "/user/roles/9", "/user/roles/-/x", "/user/name/x"} {
+		if _, err := mustPointer(t, pointer).Set(doc, 1); err == nil {
+			t.Errorf("%s: want an error", pointer)
+		}
+	}
+	if root, _ := (Pointer{}).Set(doc, "replaced"); root != "replaced" {
+		t.Errorf("the empty pointer replaces the root,got %v", root)
+	}
+}
+
+// the examples of RFC 7396 appendix A
+func TestMergePatchRFC7396(t *testing.T) {
+	var vectors = [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, vector := range vectors {
+		got, err := MergePatchJSON([]byte(vector[0]), []byte(vector[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != vector[2] {
+			t.Errorf("merge %s into %s: got %s,want %s", vector[1], vector[0], got, vector[2])
+		}
+	}
+
+	// the target is left alone
+	target := map[string]any{"a": map[string]any{"b": "c"}}
+	MergePatch(target, map[string]any{"a": map[string]any{"b": nil}})
+	if target["a"].(map[string]any)["b"] != "c" {
+		t.Errorf("MergePatch modified its target")
+	}
+}
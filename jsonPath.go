@@ -0,0 +1,744 @@
+package golangUtil
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSONPath is a compiled JSONPath query over a document tree,see ParseValue. The supported subset follows RFC 9535:
+//
+//	$                 the root
+//	.name ['name']    a member,['a','b'] picks several
+//	[0] [-1]          an element,a negative index counts from the end
+//	[start:end:step]  a slice of an array
+//	.* [*]            every member or element
+//	..name ..* ..[0]  the same at any depth
+//	[?expr]           the members or elements expr holds for,expr compares @ and $ paths and literals
+//	                  with == != < <= > >=,tests that a path exists,and combines with && || ! and parentheses
+//
+// Members are visited in the order of their names,so that the result doesn't depend on map iteration.
+type JSONPath struct {
+	expr     string
+	segments []pathSegment
+}
+
+// JSONPathError describes a malformed JSONPath expression
+type JSONPathError struct {
+	Expr   string
+	Offset int
+	msg    string
+}
+
+func (e *JSONPathError) Error() string {
+	return "jsonpath " + strconv.Quote(e.Expr) + ": " + e.msg + " at offset " + strconv.Itoa(e.Offset)
+}
+
+type pathSegment struct {
+	// the ..segment,applied to the node and every node below it
+	descendant bool
+	selectors  []pathSelector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type pathSelector struct {
+	kind  selectorKind
+	name  string
+	index int
+	// slice bounds,an absent start or end is open
+	start, end, step int
+	hasStart, hasEnd bool
+	filter           filterExpr
+}
+
+// CompileJSONPath parses a JSONPath expression such as $.store.book[?@.price < 10].title
+func CompileJSONPath(expr string) (*JSONPath, error) {
+	p := pathParser{expr: expr}
+	if !p.consume("$") {
+		return nil, p.errorf("expression must start with '$'")
+	}
+	segments, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.expr) {
+		return nil, p.errorf("unexpected " + strconv.Quote(p.expr[p.pos:p.pos+1]))
+	}
+	return &JSONPath{expr: expr, segments: segments}, nil
+}
+
+// QueryJSONPath compiles expr and runs it on doc
+func QueryJSONPath(doc any, expr string) ([]any, error) {
+	path, err := CompileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.Query(doc), nil
+}
+
+func (jp *JSONPath) String() string {
+	return jp.expr
+}
+
+// Query returns the nodes of doc the path selects,in document order
+func (jp *JSONPath) Query(doc any) []any {
+	return evalSegments(jp.segments, doc, doc)
+}
+
+func evalSegments(segments []pathSegment, root, node any) []any {
+	nodes := []any{node}
+	for _, segment := range segments {
+		var next []any
+		for _, n := range nodes {
+			if segment.descendant {
+				walkDescendants(n, func(d any) {
+					next = segment.apply(next, root, d)
+				})
+				continue
+			}
+			next = segment.apply(next, root, n)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// walkDescendants calls visit for node and every node below it,parents before their children
+func walkDescendants(node any, visit func(any)) {
+	visit(node)
+	switch n := node.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(n) {
+			walkDescendants(n[k], visit)
+		}
+	case []any:
+		for _, v := range n {
+			walkDescendants(v, visit)
+		}
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s pathSegment) apply(out []any, root, node any) []any {
+	for i := range s.selectors {
+		out = s.selectors[i].apply(out, root, node)
+	}
+	return out
+}
+
+func (sel *pathSelector) apply(out []any, root, node any) []any {
+	switch sel.kind {
+	case selectName:
+		if m, ok := node.(map[string]any); ok {
+			if v, ok := m[sel.name]; ok {
+				out = append(out, v)
+			}
+		}
+	case selectWildcard, selectFilter:
+		switch n := node.(type) {
+		case map[string]any:
+			for _, k := range sortedKeys(n) {
+				if sel.kind == selectWildcard || sel.filter.holds(root, n[k]) {
+					out = append(out, n[k])
+				}
+			}
+		case []any:
+			for _, v := range n {
+				if sel.kind == selectWildcard || sel.filter.holds(root, v) {
+					out = append(out, v)
+				}
+			}
+		}
+	case selectIndex:
+		if a, ok := node.([]any); ok {
+			i := sel.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				out = append(out, a[i])
+			}
+		}
+	case selectSlice:
+		if a, ok := node.([]any); ok && sel.step != 0 {
+			lower, upper := sel.bounds(len(a))
+			if sel.step > 0 {
+				for i := lower; i < upper; i += sel.step {
+					out = append(out, a[i])
+				}
+			} else {
+				for i := upper; lower < i; i += sel.step {
+					out = append(out, a[i])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// bounds returns the range a slice walks as RFC 9535 defines it,[lower,upper) for a positive step and (lower,upper] for a negative one
+func (sel *pathSelector) bounds(length int) (int, int) {
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	clamp := func(i, low, high int) int {
+		if i < low {
+			return low
+		}
+		if i > high {
+			return high
+		}
+		return i
+	}
+	if sel.step > 0 {
+		start, end := 0, length
+		if sel.hasStart {
+			start = normalize(sel.start)
+		}
+		if sel.hasEnd {
+			end = normalize(sel.end)
+		}
+		return clamp(start, 0, length), clamp(end, 0, length)
+	}
+	start, end := length-1, -1
+	if sel.hasStart {
+		start = normalize(sel.start)
+	}
+	if sel.hasEnd {
+		end = normalize(sel.end)
+	}
+	return clamp(end, -1, length-1), clamp(start, -1, length-1)
+}
+
+// filterExpr is the logical expression of a [?expr] selector
+type filterExpr interface {
+	holds(root, current any) bool
+}
+
+type filterOr struct{ left, right filterExpr }
+type filterAnd struct{ left, right filterExpr }
+type filterNot struct{ expr filterExpr }
+
+// filterExists holds when its path selects at least one node
+type filterExists struct{ query filterQuery }
+
+type filterCompare struct {
+	op          string
+	left, right filterOperand
+}
+
+func (f filterOr) holds(root, current any) bool {
+	return f.left.holds(root, current) || f.right.holds(root, current)
+}
+
+func (f filterAnd) holds(root, current any) bool {
+	return f.left.holds(root, current) && f.right.holds(root, current)
+}
+
+func (f filterNot) holds(root, current any) bool {
+	return !f.expr.holds(root, current)
+}
+
+func (f filterExists) holds(root, current any) bool {
+	return len(f.query.eval(root, current)) > 0
+}
+
+// filterQuery is a path inside a filter,relative to the current node with @ or absolute with $
+type filterQuery struct {
+	relative bool
+	segments []pathSegment
+}
+
+func (q filterQuery) eval(root, current any) []any {
+	if q.relative {
+		return evalSegments(q.segments, root, current)
+	}
+	return evalSegments(q.segments, root, root)
+}
+
+// filterOperand is a side of a comparison,a path or a literal
+type filterOperand struct {
+	query   *filterQuery
+	literal any
+}
+
+// value returns the operand,ok is false when a path selects nothing or more than one node
+func (o filterOperand) value(root, current any) (any, bool) {
+	if o.query == nil {
+		return o.literal, true
+	}
+	nodes := o.query.eval(root, current)
+	if len(nodes) != 1 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+func (f filterCompare) holds(root, current any) bool {
+	left, leftOk := f.left.value(root, current)
+	right, rightOk := f.right.value(root, current)
+	switch f.op {
+	case "==":
+		return compareEqual(left, leftOk, right, rightOk)
+	case "!=":
+		return !compareEqual(left, leftOk, right, rightOk)
+	case "<":
+		return leftOk && rightOk && compareLess(left, right)
+	case ">":
+		return leftOk && rightOk && compareLess(right, left)
+	case "<=":
+		return leftOk && rightOk && (compareLess(left, right) || jsonEqual(left, right))
+	default: // ">="
+		return leftOk && rightOk && (compareLess(right, left) || jsonEqual(left, right))
+	}
+}
+
+// compareEqual follows RFC 9535,two empty sides are equal and an empty side differs from any value
+func compareEqual(left any, leftOk bool, right any, rightOk bool) bool {
+	if !leftOk || !rightOk {
+		return leftOk == rightOk
+	}
+	return jsonEqual(left, right)
+}
+
+// compareLess orders two numbers or two strings,any other pair isn't ordered
+func compareLess(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x < y
+	}
+	x, ok := a.(string)
+	if !ok {
+		return false
+	}
+	y, ok := b.(string)
+	return ok && x < y
+}
+
+// jsonEqual compares two nodes as JSON values,numbers by value whatever their Go type
+func jsonEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case string, bool, nil:
+		return a == b
+	}
+	return false
+}
+
+// toFloat returns the value of a number node,a Number,a float or an integer
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case nil, string, bool, map[string]any, []any:
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+type pathParser struct {
+	expr string
+	pos  int
+}
+
+func (p *pathParser) errorf(msg string) error {
+	return &JSONPathError{Expr: p.expr, Offset: p.pos, msg: msg}
+}
+
+func (p *pathParser) consume(s string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.expr) && strings.IndexByte(" \t\n\r", p.expr[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// segments parses the segments following $ or @,it stops at the first byte that can't start a segment
+func (p *pathParser) segments() ([]pathSegment, error) {
+	var segments []pathSegment
+	for p.pos < len(p.expr) {
+		var segment pathSegment
+		var err error
+		switch {
+		case p.consume(".."):
+			segment, err = p.dotSegment(true)
+		case p.consume("."):
+			segment, err = p.dotSegment(false)
+		case p.expr[p.pos] == '[':
+			segment, err = p.bracketSegment(false)
+		default:
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// dotSegment parses what follows . or ..: a member name or *,and after .. a bracket too
+func (p *pathParser) dotSegment(descendant bool) (pathSegment, error) {
+	if p.consume("*") {
+		return pathSegment{descendant: descendant, selectors: []pathSelector{{kind: selectWildcard}}}, nil
+	}
+	if descendant && p.pos < len(p.expr) && p.expr[p.pos] == '[' {
+		return p.bracketSegment(true)
+	}
+	start := p.pos
+	for p.pos < len(p.expr) {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		if r != '_' && !unicode.IsLetter(r) && (p.pos == start || !unicode.IsDigit(r)) && r < utf8.RuneSelf {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return pathSegment{}, p.errorf("expecting a member name or '*'")
+	}
+	return pathSegment{descendant: descendant, selectors: []pathSelector{{kind: selectName, name: p.expr[start:p.pos]}}}, nil
+}
+
+// bracketSegment parses [selector,selector...] or [?expr]
+func (p *pathParser) bracketSegment(descendant bool) (pathSegment, error) {
+	segment := pathSegment{descendant: descendant}
+	// skip '['
+	p.pos++
+	for {
+		p.skipSpace()
+		selector, err := p.selector()
+		if err != nil {
+			return pathSegment{}, err
+		}
+		segment.selectors = append(segment.selectors, selector)
+		p.skipSpace()
+		if p.consume("]") {
+			return segment, nil
+		}
+		if !p.consume(",") {
+			return pathSegment{}, p.errorf("expecting ',' or ']'")
+		}
+	}
+}
+
+func (p *pathParser) selector() (pathSelector, error) {
+	if p.pos >= len(p.expr) {
+		return pathSelector{}, p.errorf("unexpected end of expression")
+	}
+	switch c := p.expr[p.pos]; {
+	case c == '*':
+		p.pos++
+		return pathSelector{kind: selectWildcard}, nil
+	case c == '\'' || c == '"':
+		name, err := p.quoted()
+		return pathSelector{kind: selectName, name: name}, err
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		filter, err := p.orExpr()
+		return pathSelector{kind: selectFilter, filter: filter}, err
+	}
+	// an index or a slice
+	sel := pathSelector{kind: selectIndex, step: 1}
+	var err error
+	if sel.hasStart, err = p.optionalInt(&sel.start); err != nil {
+		return sel, err
+	}
+	p.skipSpace()
+	if !p.consume(":") {
+		if !sel.hasStart {
+			return sel, p.errorf("expecting a selector")
+		}
+		sel.index = sel.start
+		return sel, nil
+	}
+	sel.kind = selectSlice
+	p.skipSpace()
+	if sel.hasEnd, err = p.optionalInt(&sel.end); err != nil {
+		return sel, err
+	}
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		if _, err = p.optionalInt(&sel.step); err != nil {
+			return sel, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *pathParser) optionalInt(n *int) (bool, error) {
+	start := p.pos
+	if p.pos < len(p.expr) && p.expr[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return false, nil
+	}
+	v, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return false, p.errorf("invalid integer")
+	}
+	*n = v
+	return true, nil
+}
+
+// quoted parses a string in single or double quotes with the escapes of JSON,\' included
+func (p *pathParser) quoted() (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.expr) {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.expr[p.pos]
+			p.pos++
+			switch esc {
+			case '\'', '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r, err := p.hex4()
+				if err != nil {
+					return "", err
+				}
+				if utf16.IsSurrogate(r) && p.consume("\\u") {
+					low, err := p.hex4()
+					if err != nil {
+						return "", err
+					}
+					r = utf16.DecodeRune(r, low)
+				}
+				b.WriteRune(r)
+			default:
+				p.pos--
+				return "", p.errorf("invalid escape")
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pathParser) hex4() (rune, error) {
+	if p.pos+4 > len(p.expr) {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	n, err := strconv.ParseUint(p.expr[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	p.pos += 4
+	return rune(n), nil
+}
+
+func (p *pathParser) orExpr() (filterExpr, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("||") {
+			return left, nil
+		}
+		p.skipSpace()
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left: left, right: right}
+	}
+}
+
+func (p *pathParser) andExpr() (filterExpr, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		p.skipSpace()
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left: left, right: right}
+	}
+}
+
+func (p *pathParser) unaryExpr() (filterExpr, error) {
+	if p.consume("!") {
+		p.skipSpace()
+		expr, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{expr: expr}, nil
+	}
+	if p.consume("(") {
+		p.skipSpace()
+		expr, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expecting ')'")
+		}
+		return expr, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.skipSpace()
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return filterCompare{op: op, left: left, right: right}, nil
+		}
+	}
+	if left.query == nil {
+		return nil, p.errorf("a literal must be compared")
+	}
+	return filterExists{query: *left.query}, nil
+}
+
+// operand parses a path starting with @ or $,or a literal
+func (p *pathParser) operand() (filterOperand, error) {
+	if p.pos >= len(p.expr) {
+		return filterOperand{}, p.errorf("unexpected end of expression")
+	}
+	switch c := p.expr[p.pos]; {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		return filterOperand{query: &filterQuery{relative: c == '@', segments: segments}}, nil
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		return filterOperand{literal: s}, err
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		d := decodeState{data: []byte(p.expr[start:])}
+		num, err := d.number()
+		if err != nil {
+			return filterOperand{}, p.errorf("invalid number")
+		}
+		p.pos += d.off
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			p.pos = start
+			return filterOperand{}, p.errorf("invalid number")
+		}
+		return filterOperand{literal: f}, nil
+	case p.consume("true"):
+		return filterOperand{literal: true}, nil
+	case p.consume("false"):
+		return filterOperand{literal: false}, nil
+	case p.consume("null"):
+		return filterOperand{literal: nil}, nil
+	}
+	return filterOperand{}, p.errorf("expecting a path or a literal")
+}
@@ -0,0 +1,71 @@
+package golangUtil
+
+import (
+	"errors"
+	"testing"
+)
+
+const bookstore = `{ "store": {
+	"book": [
+		{ "category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95 },
+		{ "category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99 },
+		{ "category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99 },
+		{ "category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99 }
+	],
+	"bicycle": { "color": "red", "price": 399 }
+}, "expensive": 10 }`
+
+func TestJSONPathQuery(t *testing.T) {
+	doc, err := ParseValue([]byte(bookstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors = []struct{ expr, want string }{
+		{`$.store.book[*].author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{`$..author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{`$.store.*`, `[{"color":"red","price":399},[{"author":"Nigel Rees","category":"reference","price":8.95,"title":"Sayings of the Century"},{"author":"Evelyn Waugh","category":"fiction","price":12.99,"title":"Sword of Honour"},{"author":"Herman Melville","category":"fiction","isbn":"0-553-21311-3","price":8.99,"title":"Moby Dick"},{"author":"J. R. R. Tolkien","category":"fiction","isbn":"0-395-19395-8","price":22.99,"title":"The Lord of the Rings"}]]`},
+		{`$.store..price`, `[399,8.95,12.99,8.99,22.99]`},
+		{`$..book[2].title`, `["Moby Dick"]`},
+		{`$..book[-1].title`, `["The Lord of the Rings"]`},
+		{`$..book[0,1].title`, `["Sayings of the Century","Sword of Honour"]`},
+		{`$..book[:2].title`, `["Sayings of the Century","Sword of Honour"]`},
+		{`$..book[::-2].title`, `["The Lord of the Rings","Sword of Honour"]`},
+		{`$..book[1:3].price`, `[12.99,8.99]`},
+		{`$..book[?@.isbn].title`, `["Moby Dick","The Lord of the Rings"]`},
+		{`$..book[?(@.price < 10)].title`, `["Sayings of the Century","Moby Dick"]`},
+		{`$..book[?@.price<$.expensive].title`, `["Sayings of the Century","Moby Dick"]`},
+		{`$..book[?@.category == 'fiction' && !(@.price >= 20)].title`, `["Sword of Honour","Moby Dick"]`},
+		{`$..book[?@.author == "Nigel Rees" || @.price > 20].title`, `["Sayings of the Century","The Lord of the Rings"]`},
+		{`$..book[?@.isbn != '0-553-21311-3'].title`, `["Sayings of the Century","Sword of Honour","The Lord of the Rings"]`},
+		{`$.store['bicycle']["color"]`, `["red"]`},
+		{`$..[?@.color=='red'].price`, `[399]`},
+		{`$.store.book[9]`, `[]`},
+		{`$.nothing..x`, `[]`},
+	}
+	for _, vector := range vectors {
+		nodes, err := QueryJSONPath(doc, vector.expr)
+		if err != nil {
+			t.Errorf("%s: %v", vector.expr, err)
+			continue
+		}
+		if nodes == nil {
+			nodes = []any{}
+		}
+		bytes, err := Marshal(nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != vector.want {
+			t.Errorf("%s\n got %s\nwant %s", vector.expr, bytes, vector.want)
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	var pathErr *JSONPathError
+	for _, expr := range []string{``, `store`, `$.`, `$[`, `$['a'`, `$[?@.a ==]`, `$[?1]`, `$[?(@.a]`, `$.a b`, `$['\q']`} {
+		if _, err := CompileJSONPath(expr); !errors.As(err, &pathErr) {
+			t.Errorf("%q: want JSONPathError,got %v", expr, err)
+		}
+	}
+}
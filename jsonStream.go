@@ -0,0 +1,468 @@
+package golangUtil
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+)
+
+// streamFlushSize is the amount of output an Encoder buffers before handing it to the writer while a big value is still being encoded
+const streamFlushSize = 32 << 10
+
+// An Encoder writes JSON values to an output stream,one value per line,which makes it suitable for newline-delimited JSON.
+// Small values are written with a single call to the writer,a value bigger than streamFlushSize is written in chunks
+// as it is encoded so that the whole payload is never buffered.
+type Encoder struct {
+	w        io.Writer
+	err      error
+	prefix   string
+	indent   string
+	indenter indenter
+	// reused output of the indenter
+	indentBuf []byte
+	// 0 means DefaultMaxDepth
+	maxDepth int
+}
+
+// NewEncoder returns a new encoder that writes to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the JSON encoding of v to the stream followed by a newline.
+// The encoding is the one Marshal produces. When v is big enough to be written in chunks
+// and encoding fails halfway,the part written before the failure stays in the stream.
+// A write error is sticky,every later call returns it.
+func (enc *Encoder) Encode(v any) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+	e.stream = enc
+	if enc.maxDepth > 0 {
+		e.maxDepth = enc.maxDepth
+	}
+	enc.indenter = indenter{}
+	err := e.marshal(reflect.ValueOf(v))
+	e.stream = nil
+	if err != nil {
+		return err
+	}
+	e.WriteByte('\n')
+	return enc.write(e.buf)
+}
+
+// SetIndent makes the encoder format each value with every element on its own line,
+// beginning with prefix and indented by one copy of indent per nesting level.
+// SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+}
+
+// SetMaxDepth limits how deep the values passed to Encode may nest,a deeper value fails with a DepthError.
+// n <= 0 restores DefaultMaxDepth.
+func (enc *Encoder) SetMaxDepth(n int) {
+	enc.maxDepth = n
+}
+
+func (enc *Encoder) write(b []byte) error {
+	if enc.prefix != "" || enc.indent != "" {
+		enc.indentBuf = enc.indenter.indent(enc.indentBuf[:0], b, enc.prefix, enc.indent)
+		b = enc.indentBuf
+	}
+	if _, err := enc.w.Write(b); err != nil {
+		enc.err = err
+		return err
+	}
+	return nil
+}
+
+// indenter formats compact JSON chunk by chunk,its state carries over from one chunk to the next
+type indenter struct {
+	depth    int
+	inString bool
+	escaped  bool
+	// the newline after an opening bracket is held back until the next byte,so that empty containers stay {} and []
+	pendingOpen bool
+}
+
+func (ind *indenter) indent(dst []byte, src []byte, prefix, indent string) []byte {
+	for _, c := range src {
+		if ind.inString {
+			dst = append(dst, c)
+			if ind.escaped {
+				ind.escaped = false
+			} else if c == '\\' {
+				ind.escaped = true
+			} else if c == '"' {
+				ind.inString = false
+			}
+			continue
+		}
+		if ind.pendingOpen {
+			ind.pendingOpen = false
+			if c == '}' || c == ']' {
+				ind.depth--
+				dst = append(dst, c)
+				continue
+			}
+			dst = newline(dst, prefix, indent, ind.depth)
+		}
+		switch c {
+		case '"':
+			ind.inString = true
+			dst = append(dst, c)
+		case '{', '[':
+			dst = append(dst, c)
+			ind.depth++
+			ind.pendingOpen = true
+		case '}', ']':
+			ind.depth--
+			dst = newline(dst, prefix, indent, ind.depth)
+			dst = append(dst, c)
+		case ',':
+			dst = append(dst, c)
+			dst = newline(dst, prefix, indent, ind.depth)
+		case ':':
+			dst = append(dst, c, ' ')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func newline(dst []byte, prefix, indent string, depth int) []byte {
+	dst = append(dst, '\n')
+	dst = append(dst, prefix...)
+	for i := 0; i < depth; i++ {
+		dst = append(dst, indent...)
+	}
+	return dst
+}
+
+// A Token holds a value of one of these types:
+// Delim for the four JSON delimiters [ ] { },bool,float64,string for strings and object keys,nil for null
+type Token any
+
+// A Delim is a JSON array or object delimiter,one of [ ] { or }
+type Delim rune
+
+func (d Delim) String() string {
+	return string(d)
+}
+
+// states of the Token reader,they say what may come next
+const (
+	tokenTopValue = iota
+	tokenArrayStart
+	tokenArrayValue
+	tokenArrayComma
+	tokenObjectStart
+	tokenObjectKey
+	tokenObjectColon
+	tokenObjectValue
+	tokenObjectComma
+)
+
+// minRead is the smallest read a Decoder asks of its reader
+const minRead = 512
+
+// A Decoder reads JSON values from an input stream,values may follow each other separated by whitespace,
+// as in newline-delimited JSON. It only buffers the input of the value being decoded.
+type Decoder struct {
+	r   io.Reader
+	buf []byte
+	// start of the unread data in buf
+	scanp int
+	// bytes dropped from the front of buf,so that error offsets count from the start of the stream
+	scanned int64
+	// error returned by the reader,io.EOF included
+	readErr error
+	// sticky error of the decoder
+	err error
+
+	tokenState int
+	tokenStack []int
+	// numbers decoded into an interface are Number instead of float64
+	useNumber bool
+}
+
+// NewDecoder returns a new decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next JSON value from the stream and stores it in the value pointed to by v,the same way Unmarshal does.
+// At the end of the stream it returns io.EOF,a stream ending in the middle of a value returns io.ErrUnexpectedEOF.
+func (dec *Decoder) Decode(v any) error {
+	if dec.err != nil {
+		return dec.err
+	}
+	if err := dec.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	if !dec.tokenValueAllowed() {
+		return &SyntaxError{msg: "not at beginning of value", Offset: dec.InputOffset()}
+	}
+	n, err := dec.readValue()
+	if err != nil {
+		return err
+	}
+	data := dec.buf[dec.scanp : dec.scanp+n]
+	dec.scanp += n
+	// the value is known to be well formed,only type errors are left
+	err = unmarshal(data, v, dec.useNumber)
+	dec.tokenValueEnd()
+	return err
+}
+
+// UseNumber makes the decoder store numbers in an interface as a Number instead of a float64,
+// so that big integers keep all their digits
+func (dec *Decoder) UseNumber() {
+	dec.useNumber = true
+}
+
+// More reports whether there is another element in the current array or object being parsed,
+// or another value in the stream at the top level
+func (dec *Decoder) More() bool {
+	c, err := dec.peek()
+	return err == nil && c != ']' && c != '}'
+}
+
+// Buffered returns a reader of the data remaining in the decoder's buffer,it is valid until the next call to Decode
+func (dec *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(dec.buf[dec.scanp:])
+}
+
+// InputOffset returns the offset in the stream of the next byte the decoder will read
+func (dec *Decoder) InputOffset() int64 {
+	return dec.scanned + int64(dec.scanp)
+}
+
+// readValue reads until buf holds the whole next value and returns the length of the whitespace and value that follow scanp
+func (dec *Decoder) readValue() (int, error) {
+	for {
+		if dec.scanp < len(dec.buf) {
+			d := decodeState{data: dec.buf[dec.scanp:]}
+			d.skipWhitespace()
+			if d.off < len(d.data) {
+				start := d.off
+				err := d.value(reflect.Value{})
+				if err == nil {
+					// a number running up to the end of the buffer may go on in the next read
+					c := d.data[start]
+					if d.off < len(d.data) || dec.readErr != nil || c != '-' && (c < '0' || c > '9') {
+						return d.off, nil
+					}
+				} else if syntaxErr := err.(*SyntaxError); syntaxErr.msg != errUnexpectedEnd {
+					syntaxErr.Offset += dec.InputOffset()
+					dec.err = err
+					return 0, err
+				}
+			}
+		}
+		if dec.readErr != nil {
+			if dec.readErr != io.EOF {
+				dec.err = dec.readErr
+			} else if len(bytes.TrimLeft(dec.buf[dec.scanp:], " \t\r\n")) != 0 {
+				dec.err = io.ErrUnexpectedEOF
+			} else {
+				dec.err = io.EOF
+			}
+			return 0, dec.err
+		}
+		dec.refill()
+	}
+}
+
+// refill drops the consumed data and reads more,the room offered to the reader is at least the size of the pending data
+// so that a reader filling it rescans a big value a logarithmic number of times
+func (dec *Decoder) refill() {
+	if dec.scanp > 0 {
+		dec.scanned += int64(dec.scanp)
+		n := copy(dec.buf, dec.buf[dec.scanp:])
+		dec.buf = dec.buf[:n]
+		dec.scanp = 0
+	}
+	if free := cap(dec.buf) - len(dec.buf); free < minRead || free < len(dec.buf) {
+		grown := make([]byte, len(dec.buf), 2*cap(dec.buf)+minRead)
+		copy(grown, dec.buf)
+		dec.buf = grown
+	}
+	n, err := dec.r.Read(dec.buf[len(dec.buf):cap(dec.buf)])
+	dec.buf = dec.buf[:len(dec.buf)+n]
+	if err != nil {
+		dec.readErr = err
+	}
+}
+
+// peek returns the next non-space byte without consuming it
+func (dec *Decoder) peek() (byte, error) {
+	for {
+		for i := dec.scanp; i < len(dec.buf); i++ {
+			switch c := dec.buf[i]; c {
+			case ' ', '\t', '\r', '\n':
+			default:
+				dec.scanp = i
+				return c, nil
+			}
+		}
+		dec.scanp = len(dec.buf)
+		if dec.readErr != nil {
+			return 0, dec.readErr
+		}
+		dec.refill()
+	}
+}
+
+// Token returns the next JSON token in the stream,delimiters come back as Delim while commas and colons are consumed silently.
+// At the end of the stream Token returns nil,io.EOF. Token and Decode can be mixed to stream the elements of a big array one by one.
+func (dec *Decoder) Token() (Token, error) {
+	for {
+		c, err := dec.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '[':
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenStack = append(dec.tokenStack, dec.tokenState)
+			dec.tokenState = tokenArrayStart
+			return Delim('['), nil
+		case ']':
+			if dec.tokenState != tokenArrayStart && dec.tokenState != tokenArrayComma {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = dec.tokenStack[len(dec.tokenStack)-1]
+			dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+			dec.tokenValueEnd()
+			return Delim(']'), nil
+		case '{':
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenStack = append(dec.tokenStack, dec.tokenState)
+			dec.tokenState = tokenObjectStart
+			return Delim('{'), nil
+		case '}':
+			if dec.tokenState != tokenObjectStart && dec.tokenState != tokenObjectComma {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = dec.tokenStack[len(dec.tokenStack)-1]
+			dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+			dec.tokenValueEnd()
+			return Delim('}'), nil
+		case ':':
+			if dec.tokenState != tokenObjectColon {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = tokenObjectValue
+			continue
+		case ',':
+			if dec.tokenState == tokenArrayComma {
+				dec.scanp++
+				dec.tokenState = tokenArrayValue
+				continue
+			}
+			if dec.tokenState == tokenObjectComma {
+				dec.scanp++
+				dec.tokenState = tokenObjectKey
+				continue
+			}
+			return dec.tokenError(c)
+		case '"':
+			if dec.tokenState == tokenObjectStart || dec.tokenState == tokenObjectKey {
+				var key string
+				old := dec.tokenState
+				dec.tokenState = tokenTopValue
+				err := dec.Decode(&key)
+				dec.tokenState = old
+				if err != nil {
+					return nil, err
+				}
+				dec.tokenState = tokenObjectColon
+				return key, nil
+			}
+			fallthrough
+		default:
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			var x any
+			if err := dec.Decode(&x); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+}
+
+func (dec *Decoder) tokenPrepareForDecode() error {
+	switch dec.tokenState {
+	case tokenArrayComma:
+		c, err := dec.peek()
+		if err != nil {
+			return err
+		}
+		if c != ',' {
+			return &SyntaxError{msg: "expected comma after array element", Offset: dec.InputOffset()}
+		}
+		dec.scanp++
+		dec.tokenState = tokenArrayValue
+	case tokenObjectColon:
+		c, err := dec.peek()
+		if err != nil {
+			return err
+		}
+		if c != ':' {
+			return &SyntaxError{msg: "expected colon after object key", Offset: dec.InputOffset()}
+		}
+		dec.scanp++
+		dec.tokenState = tokenObjectValue
+	}
+	return nil
+}
+
+func (dec *Decoder) tokenValueAllowed() bool {
+	switch dec.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		return true
+	}
+	return false
+}
+
+func (dec *Decoder) tokenValueEnd() {
+	switch dec.tokenState {
+	case tokenArrayStart, tokenArrayValue:
+		dec.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		dec.tokenState = tokenObjectComma
+	}
+}
+
+func (dec *Decoder) tokenError(c byte) (Token, error) {
+	var context string
+	switch dec.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		context = " looking for beginning of value"
+	case tokenArrayComma:
+		context = " after array element"
+	case tokenObjectKey:
+		context = " looking for beginning of object key string"
+	case tokenObjectColon:
+		context = " after object key"
+	case tokenObjectComma:
+		context = " after object key:value pair"
+	}
+	return nil, &SyntaxError{msg: "invalid character " + quoteChar(c) + context, Offset: dec.InputOffset()}
+}
@@ -0,0 +1,241 @@
+package golangUtil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoderMatchesEncodingJson(t *testing.T) {
+	for _, indent := range [][2]string{{"", ""}, {"", "  "}, {">", "\t"}} {
+		var got, want bytes.Buffer
+		enc := NewEncoder(&got)
+		enc.SetIndent(indent[0], indent[1])
+		rightEnc := json.NewEncoder(&want)
+		rightEnc.SetIndent(indent[0], indent[1])
+		for _, value := range differentialCorpus {
+			if err := enc.Encode(value); err != nil {
+				t.Fatalf("encode %#v: %v", value, err)
+			}
+			if err := rightEnc.Encode(value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got.String() != want.String() {
+			t.Errorf("indent %q\n got %s\nwant %s", indent, got.String(), want.String())
+		}
+	}
+}
+
+// countingWriter records the size of every write
+type countingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestEncoderStreamsBigValues(t *testing.T) {
+	var items = make([]benchItem, 2000)
+	for i := range items {
+		items[i] = newBenchOrder().Items[i%10]
+	}
+	for _, indent := range []string{"", "  "} {
+		w := &countingWriter{}
+		enc := NewEncoder(w)
+		enc.SetIndent("", indent)
+		if err := enc.Encode(items); err != nil {
+			t.Fatal(err)
+		}
+		if len(w.writes) < 2 {
+			t.Errorf("a %d byte value should be written in chunks,got %v", w.Len(), w.writes)
+		}
+		for _, n := range w.writes {
+			if indent == "" && n > 2*streamFlushSize {
+				t.Errorf("chunk of %d bytes exceeds the flush size", n)
+			}
+		}
+		var want bytes.Buffer
+		rightEnc := json.NewEncoder(&want)
+		rightEnc.SetIndent("", indent)
+		if err := rightEnc.Encode(items); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != want.String() {
+			t.Errorf("streamed output differs from encoding/json")
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestEncoderStickyError(t *testing.T) {
+	enc := NewEncoder(failingWriter{})
+	if err := enc.Encode(1); err == nil {
+		t.Fatal("want write error")
+	}
+	if err := enc.Encode(2); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("want the sticky write error,got %v", err)
+	}
+}
+
+const ndjsonStream = `{"name":"a","tags":[1,2]}
+{"name":"b","tags":[]}
+ [1, "two", null, {"three": 3.5}]
+"text" 12345 -0.5e3 true false null
+{"nested":{"deep":[{"k":"vé\n"}]}}`
+
+func TestDecoderNDJSON(t *testing.T) {
+	readers := map[string]func() io.Reader{
+		"whole":    func() io.Reader { return strings.NewReader(ndjsonStream) },
+		"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(ndjsonStream)) },
+		"half":     func() io.Reader { return iotest.HalfReader(strings.NewReader(ndjsonStream)) },
+		"data err": func() io.Reader { return iotest.DataErrReader(strings.NewReader(ndjsonStream)) },
+	}
+	for name, reader := range readers {
+		dec := NewDecoder(reader())
+		rightDec := json.NewDecoder(strings.NewReader(ndjsonStream))
+		for {
+			var got, want any
+			err := dec.Decode(&got)
+			rightErr := rightDec.Decode(&want)
+			if rightErr == io.EOF {
+				if err != io.EOF {
+					t.Errorf("%s: want io.EOF,got %v", name, err)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %#v,want %#v", name, got, want)
+			}
+			if dec.InputOffset() != rightDec.InputOffset() {
+				t.Errorf("%s: offset %d,want %d", name, dec.InputOffset(), rightDec.InputOffset())
+			}
+		}
+	}
+}
+
+func TestDecoderTokens(t *testing.T) {
+	const doc = `{"items":[{"sku":"a","quantity":1},{"sku":"b","quantity":2}],"total":3,"empty":{},"list":[[],[null]]} "next"`
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(doc)))
+	rightDec := json.NewDecoder(strings.NewReader(doc))
+	for {
+		got, err := dec.Token()
+		want, rightErr := rightDec.Token()
+		if rightErr == io.EOF {
+			if err != io.EOF {
+				t.Errorf("want io.EOF,got %v", err)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmtToken(got) != fmtToken(want) {
+			t.Fatalf("token %v,want %v", got, want)
+		}
+		if dec.More() != rightDec.More() {
+			t.Fatalf("More after %v differs", got)
+		}
+	}
+
+	// tokens and Decode mixed,the elements of a big array are decoded one by one
+	dec = NewDecoder(strings.NewReader(doc))
+	for _, want := range []Token{Delim('{'), "items", Delim('[')} {
+		if got, err := dec.Token(); err != nil || got != want {
+			t.Fatalf("token %v %v,want %v", got, err, want)
+		}
+	}
+	var skus []string
+	for dec.More() {
+		var item struct {
+			SKU string `json:"sku"`
+		}
+		if err := dec.Decode(&item); err != nil {
+			t.Fatal(err)
+		}
+		skus = append(skus, item.SKU)
+	}
+	if !reflect.DeepEqual(skus, []string{"a", "b"}) {
+		t.Errorf("got %v", skus)
+	}
+	if got, err := dec.Token(); err != nil || got != Delim(']') {
+		t.Errorf("token %v %v,want ]", got, err)
+	}
+}
+
+func fmtToken(token Token) string {
+	if d, ok := token.(Delim); ok {
+		return "delim " + d.String()
+	}
+	if d, ok := token.(json.Delim); ok {
+		return "delim " + d.String()
+	}
+	bytes, _ := json.Marshal(token)
+	return string(bytes)
+}
+
+func TestDecoderErrors(t *testing.T) {
+	var v any
+	if err := NewDecoder(strings.NewReader(`  `)).Decode(&v); err != io.EOF {
+		t.Errorf("want io.EOF,got %v", err)
+	}
+	if err := NewDecoder(strings.NewReader(`{"a":[1,`)).Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("want io.ErrUnexpectedEOF,got %v", err)
+	}
+	dec := NewDecoder(strings.NewReader(`{"a":1} {"a" 1}`))
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	var syntaxErr *SyntaxError
+	if err := dec.Decode(&v); !errors.As(err, &syntaxErr) || syntaxErr.Offset != 13 {
+		t.Errorf("want SyntaxError at offset 13,got %v", err)
+	}
+	// a type error doesn't stop the stream
+	dec = NewDecoder(strings.NewReader(`{"A":"x"} {"A":2}`))
+	var target struct{ A int }
+	var typeErr *UnmarshalTypeError
+	if err := dec.Decode(&target); !errors.As(err, &typeErr) {
+		t.Errorf("want UnmarshalTypeError,got %v", err)
+	}
+	if err := dec.Decode(&target); err != nil || target.A != 2 {
+		t.Errorf("got %v %v", target, err)
+	}
+}
+
+func BenchmarkGolangUtilEncoderNDJSON(b *testing.B) {
+	order := newBenchOrder()
+	enc := NewEncoder(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONEncoderNDJSON(b *testing.B) {
+	order := newBenchOrder()
+	enc := json.NewEncoder(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
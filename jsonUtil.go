@@ -1,303 +1,1011 @@
 package golangUtil
 
 import (
+	"encoding"
+	"encoding/base64"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 )
 
-const MaxStorageBytes int64 = 1024
-const MaxTolerateCacheFailedRate = 0.3
-const RetryStoreCacheTimes = 10
-const ArrangeCacheTimes = 1000
-
-type nocopy uintptr
-type jsonEncoder struct {
-	cache             map[uintptr]*[]byte
-	obsolete          heap
-	cacheAccessTimes  map[uintptr]int64
-	capacity          int64
-	nocopy            nocopy
-	rw                sync.RWMutex
-	cacheInValidTimes int
-	//the field to order the cache invalid info
-	cacheSuccessNums int64
-	cacheNums        int64
-}
-
-func init() {
-	JsonEncoder = &jsonEncoder{
-		cache:    make(map[uintptr]*[]byte),
-		capacity: 0,
-		obsolete: heap{
-			heapArr: make([]int, 100),
-			value:   make([]uintptr, 100),
-			length:  0,
-		},
-		rw:               sync.RWMutex{},
-		cacheAccessTimes: make(map[uintptr]int64),
-	}
-	JsonEncoder.nocopy = nocopy(unsafe.Pointer(JsonEncoder))
+// Marshal returns the JSON encoding of value,the output is RFC 8259 compliant and byte for byte the same as encoding/json produces:
+// object members are written in struct field order,map keys are sorted,strings are escaped and []byte is written as base64.
+// The encoder of each type is compiled once on first use and shared afterwards,so repeated marshaling of a type skips the reflection walk.
+func Marshal(value any) ([]byte, error) {
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+	err := e.marshal(reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), e.Bytes()...), nil
 }
 
-var (
-	JsonEncoder *jsonEncoder
-)
+// UnsupportedTypeError is returned by Marshal when attempting to encode a type JSON can't represent,such as a channel,a function or a complex number
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
 
-func (e *jsonEncoder) store(address uintptr, data *[]byte) {
-	e.check()
-	e.rw.Lock()
-	defer e.rw.Unlock()
-	if _, ok := e.cache[address]; ok {
-		return
-	}
-	var cacheSuccessNums = e.cacheSuccessNums
-	var cacheNums = e.cacheNums
-	//cache drain strategy
-	if e.capacity+int64(len(*data)) > MaxStorageBytes {
-		if int64(e.obsolete.peekMax())+MaxStorageBytes-e.capacity < int64(len(*data)) && e.cacheInValidTimes < RetryStoreCacheTimes {
-			e.cacheInValidTimes++
-			return
-		} else if e.cacheInValidTimes >= RetryStoreCacheTimes {
-			value := e.obsolete.peekMax()
-			if int64(len(*e.cache[value])) > MaxStorageBytes/4 {
-				delete(e.cache, value)
-				e.obsolete.delete(e.obsolete.length)
-				delete(e.cacheAccessTimes, value)
-			} else if float64(cacheSuccessNums/cacheNums) < MaxTolerateCacheFailedRate {
-				var sum = int64(e.obsolete.length)
-				for i := 0; i > e.obsolete.length; i++ {
-					var temp = e.obsolete.value[e.obsolete.length]
-					//can't give up the hot data
-					if e.cacheAccessTimes[temp] > cacheSuccessNums/sum*2 {
-						continue
-					}
-					delete(e.cache, temp)
-					delete(e.cacheAccessTimes, temp)
-					e.obsolete.delete(i)
-				}
-				e.cacheNums = 0
-				e.cacheSuccessNums = 0
-			}
-			e.cacheInValidTimes = 0
-		} else if int64(e.obsolete.peekMax())+MaxStorageBytes-e.capacity >= int64(len(*data)) {
-			if e.cacheAccessTimes[e.obsolete.peekMax()] <= cacheSuccessNums/int64(len(e.cache))*2 {
-				var temp = e.obsolete.peekMax()
-				delete(e.cache, temp)
-				delete(e.cacheAccessTimes, temp)
-				e.obsolete.delete(1)
-			}
+func (e *UnsupportedTypeError) Error() string {
+	return "json: unsupported type: " + e.Type.String()
+}
+
+// UnsupportedValueError is returned by Marshal when attempting to encode a value JSON can't represent,such as NaN or an infinity
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "json: unsupported value: " + e.Str
+}
+
+// DefaultMaxDepth is the nesting depth Marshal allows before it gives up with a DepthError,
+// the same limit encoding/json applies when decoding
+const DefaultMaxDepth = 10000
+
+// CycleError is returned by Marshal when a pointer,map or slice contains itself,
+// Path names the place where the value comes back to itself,e.g. $.Children[3].Parent
+type CycleError struct {
+	Type reflect.Type
+	Path string
+	path valuePath
+}
+
+func (e *CycleError) Error() string {
+	return "json: encountered a cycle via " + e.Type.String() + " at " + e.Path
+}
+
+// DepthError is returned by Marshal when a value nests deeper than the depth limit,Path names the first value past it
+type DepthError struct {
+	MaxDepth int
+	Path     string
+	path     valuePath
+}
+
+func (e *DepthError) Error() string {
+	return "json: exceeded max depth " + strconv.Itoa(e.MaxDepth) + " at " + e.Path
+}
+
+// pathError is an error that knows where in the value it happened,
+// the encoders add their segment to it while they unwind
+type pathError interface {
+	error
+	addSegment(segment string)
+	resolvePath()
+}
+
+func (e *CycleError) addSegment(segment string) { e.path = append(e.path, segment) }
+func (e *CycleError) resolvePath()              { e.Path = e.path.String() }
+func (e *DepthError) addSegment(segment string) { e.path = append(e.path, segment) }
+func (e *DepthError) resolvePath()              { e.Path = e.path.String() }
+
+// valuePath holds the segments of a path innermost first,in the order the encoders unwind
+type valuePath []string
+
+func (p valuePath) String() string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for i := len(p) - 1; i >= 0; i-- {
+		b.WriteString(p[i])
+	}
+	return b.String()
+}
+
+// withSegment adds the segment of a child value to err when it records a path
+func withSegment(err error, segment func() string) error {
+	if pe, ok := err.(pathError); ok {
+		pe.addSegment(segment())
+	}
+	return err
+}
+
+// keySegment is the path segment of an object member,.name when the name is a plain identifier and ["name"] otherwise
+func keySegment(name string) string {
+	for i, c := range name {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return "[" + strconv.Quote(name) + "]"
 		}
 	}
-	if e.cacheNums > ArrangeCacheTimes {
-		if float64(cacheSuccessNums/cacheNums) < MaxTolerateCacheFailedRate {
-			var sum = int64(e.obsolete.length)
-			for i := 0; i > e.obsolete.length; i++ {
-				var temp = e.obsolete.value[e.obsolete.length]
-				//can't give up the hot data
-				if e.cacheAccessTimes[temp] > cacheSuccessNums/sum {
-					continue
-				}
-				delete(e.cache, temp)
-				delete(e.cacheAccessTimes, temp)
-				e.obsolete.delete(i)
+	if name == "" {
+		return `[""]`
+	}
+	return "." + name
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// encodeState appends the output to buf,encoders write straight into it without intermediate copies
+type encodeState struct {
+	buf []byte
+	// set while an Encoder streams a value,buf is handed to it once it grows past streamFlushSize
+	stream *Encoder
+
+	// nesting of the value being written,objects and arrays count one level each
+	depth    int
+	maxDepth int
+	// the pointers,maps and slices from the root down to the value being written,a value met again among them is a cycle.
+	// the stack is scanned while it is short,ptrSeen indexes it once it grows past ptrScanLimit
+	ptrStack []ptrKey
+	ptrSeen  map[ptrKey]struct{}
+
+	// write the RFC 8785 canonical form,see MarshalCanonical
+	canonical bool
+}
+
+type ptrKey struct {
+	ptr unsafe.Pointer
+	// a struct and its first field share an address,the type tells them apart
+	typ reflect.Type
+	// subslices share a backing array,only the same slice is a cycle
+	len int
+}
+
+const ptrScanLimit = 32
+
+// pushPtr records value on the path being written,it fails when value is already on it
+func (e *encodeState) pushPtr(value reflect.Value, length int) error {
+	key := ptrKey{ptr: value.UnsafePointer(), typ: value.Type(), len: length}
+	if e.ptrSeen != nil {
+		if _, ok := e.ptrSeen[key]; ok {
+			return &CycleError{Type: value.Type()}
+		}
+		e.ptrSeen[key] = struct{}{}
+	} else {
+		for _, k := range e.ptrStack {
+			if k == key {
+				return &CycleError{Type: value.Type()}
 			}
-			e.cacheNums = 0
-			e.cacheSuccessNums = 0
-			e.cacheInValidTimes = 0
+		}
+		if len(e.ptrStack) >= ptrScanLimit {
+			e.ptrSeen = make(map[ptrKey]struct{}, 2*len(e.ptrStack))
+			for _, k := range e.ptrStack {
+				e.ptrSeen[k] = struct{}{}
+			}
+			e.ptrSeen[key] = struct{}{}
 		}
 	}
-	atomic.AddInt64(&e.capacity, int64(len(*data)))
-	e.obsolete.insert(len(*data), address)
-	e.cacheAccessTimes[address] = 0
-	e.cache[address] = data
+	e.ptrStack = append(e.ptrStack, key)
+	return nil
 }
 
-func (e *jsonEncoder) load(address uintptr) (*[]byte, bool) {
-	e.check()
-	e.rw.RLock()
-	defer e.rw.RUnlock()
-	atomic.AddInt64(&e.cacheNums, 1)
-	value, ok := e.cache[address]
-	if ok {
-		times := e.cacheAccessTimes[address]
-		atomic.AddInt64(&times, 1)
-		atomic.AddInt64(&e.cacheSuccessNums, 1)
+func (e *encodeState) popPtr() {
+	key := e.ptrStack[len(e.ptrStack)-1]
+	e.ptrStack = e.ptrStack[:len(e.ptrStack)-1]
+	if e.ptrSeen != nil {
+		delete(e.ptrSeen, key)
 	}
-	return value, ok
 }
-func (e *jsonEncoder) check() {
-	if uintptr(e.nocopy) != uintptr(unsafe.Pointer(e)) &&
-		!atomic.CompareAndSwapUintptr((*uintptr)(&e.nocopy), 0, uintptr(unsafe.Pointer(e))) &&
-		uintptr(e.nocopy) != uintptr(unsafe.Pointer(e)) {
-		panic(any("object has copyed"))
+
+// enter steps one level down into an object or an array
+func (e *encodeState) enter() error {
+	e.depth++
+	if e.depth > e.maxDepth {
+		return &DepthError{MaxDepth: e.maxDepth}
 	}
+	return nil
 }
 
-func Marshal(value any) ([]byte, error) {
-	var address uintptr
-	var load *[]byte
-	var ok bool
-	if value, ok := value.(unsafe.Pointer); ok {
-		address = uintptr(value)
-	} else {
-		address = uintptr(unsafe.Pointer(&value))
+// flush hands the buffered output to the stream between two elements of a big value
+func (e *encodeState) flush() error {
+	if e.stream == nil || len(e.buf) < streamFlushSize {
+		return nil
+	}
+	if err := e.stream.write(e.buf); err != nil {
+		return err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+func (e *encodeState) WriteString(s string) {
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encodeState) WriteByte(c byte) error {
+	e.buf = append(e.buf, c)
+	return nil
+}
+
+func (e *encodeState) Write(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encodeState) Bytes() []byte {
+	return e.buf
+}
+
+func (e *encodeState) Reset() {
+	e.buf = e.buf[:0]
+}
+
+var encodeStatePool sync.Pool
+
+func newEncodeState() *encodeState {
+	if v := encodeStatePool.Get(); v != nil {
+		e := v.(*encodeState)
+		e.Reset()
+		e.stream = nil
+		// a failed encoding leaves its path behind
+		e.depth = 0
+		e.maxDepth = DefaultMaxDepth
+		e.ptrStack = e.ptrStack[:0]
+		e.ptrSeen = nil
+		e.canonical = false
+		return e
 	}
-	load, ok = JsonEncoder.load(address)
-	if ok {
-		return *load, nil
+	return &encodeState{maxDepth: DefaultMaxDepth}
+}
+
+type encOpts struct {
+	// write the value inside a JSON string,the ,string tag option
+	quoted bool
+}
+
+func (e *encodeState) marshal(value reflect.Value) error {
+	err := e.reflectValue(value, encOpts{})
+	if pe, ok := err.(pathError); ok {
+		pe.resolvePath()
+	}
+	return err
+}
+
+func (e *encodeState) reflectValue(value reflect.Value, opts encOpts) error {
+	return valueEncoder(value)(e, value, opts)
+}
+
+// encoderFunc writes one value of the type it was compiled for
+type encoderFunc func(e *encodeState, value reflect.Value, opts encOpts) error
+
+var encoderCache sync.Map // map[reflect.Type]encoderFunc
+
+func valueEncoder(value reflect.Value) encoderFunc {
+	if !value.IsValid() {
+		return invalidValueEncoder
+	}
+	return typeEncoder(value.Type())
+}
+
+// typeEncoder returns the compiled encoder of t,building it on first use
+func typeEncoder(t reflect.Type) encoderFunc {
+	if fi, ok := encoderCache.Load(t); ok {
+		return fi.(encoderFunc)
+	}
+	// a recursive type reaches itself while its encoder is being built,
+	// the placeholder waits for the real encoder and then forwards to it
+	var (
+		wg sync.WaitGroup
+		f  encoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := encoderCache.LoadOrStore(t, encoderFunc(func(e *encodeState, value reflect.Value, opts encOpts) error {
+		wg.Wait()
+		return f(e, value, opts)
+	}))
+	if loaded {
+		return fi.(encoderFunc)
+	}
+	f = newTypeEncoder(t, true)
+	wg.Done()
+	encoderCache.Store(t, f)
+	return f
+}
+
+// newTypeEncoder compiles t,a type with its own marshaling method is written through it before any reflection.
+// allowAddr says whether pointer receiver methods may be used when the value is addressable,
+// the fallback for a non addressable value is compiled with allowAddr false.
+func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if t == timeType {
+		return timeEncoder
+	}
+	if isNumberType(t) {
+		return numberEncoder
+	}
+	if t.Kind() != reflect.Pointer && allowAddr && reflect.PointerTo(t).Implements(marshalerType) {
+		return newCondAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, false))
+	}
+	if t.Implements(marshalerType) {
+		return marshalerEncoder
+	}
+	if t.Kind() != reflect.Pointer && allowAddr && reflect.PointerTo(t).Implements(textMarshalerType) {
+		return newCondAddrEncoder(addrTextMarshalerEncoder, newTypeEncoder(t, false))
+	}
+	if t.Implements(textMarshalerType) {
+		return textMarshalerEncoder
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32:
+		return float32Encoder
+	case reflect.Float64:
+		return float64Encoder
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Slice:
+		return newSliceEncoder(t)
+	case reflect.Array:
+		return newArrayEncoder(t)
+	case reflect.Pointer:
+		return newPtrEncoder(t)
+	default:
+		return unsupportedTypeEncoder
+	}
+}
+
+func invalidValueEncoder(e *encodeState, value reflect.Value, _ encOpts) error {
+	e.WriteString("null")
+	return nil
+}
+
+func unsupportedTypeEncoder(e *encodeState, value reflect.Value, _ encOpts) error {
+	return &UnsupportedTypeError{Type: value.Type()}
+}
+
+func boolEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	e.buf = strconv.AppendBool(e.buf, value.Bool())
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	return nil
+}
+
+func intEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	n := value.Int()
+	if e.canonical && !opts.quoted && (n > maxExactInt || n < -maxExactInt) {
+		return &UnsupportedValueError{Value: value, Str: strconv.FormatInt(n, 10) + " is out of the exact range of a double"}
+	}
+	e.buf = strconv.AppendInt(e.buf, n, 10)
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	return nil
+}
+
+func uintEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	n := value.Uint()
+	if e.canonical && !opts.quoted && n > maxExactInt {
+		return &UnsupportedValueError{Value: value, Str: strconv.FormatUint(n, 10) + " is out of the exact range of a double"}
+	}
+	e.buf = strconv.AppendUint(e.buf, n, 10)
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	return nil
+}
+
+type floatEncoder int // number of bits
+
+func (bits floatEncoder) encode(e *encodeState, value reflect.Value, opts encOpts) error {
+	n := len(e.buf)
+	if opts.quoted {
+		e.WriteByte('"')
+	}
+	var b []byte
+	var err error
+	if e.canonical {
+		b, err = appendCanonicalFloat(e.buf, value)
+	} else {
+		b, err = appendFloat(e.buf, value, int(bits))
 	}
-	sb := strings.Builder{}
-	bytes, err := marshal(reflect.ValueOf(value))
 	if err != nil {
-		return nil, err
+		e.buf = e.buf[:n]
+		return err
+	}
+	e.buf = b
+	if opts.quoted {
+		e.WriteByte('"')
 	}
-	sb.WriteString("{")
-	sb.WriteString(string(bytes))
-	sb.WriteString("}")
-	bytes = []byte(sb.String())
-	JsonEncoder.store(address, &bytes)
-	return bytes, nil
+	return nil
 }
-func isInvalid(value reflect.Value) bool {
-	if value.Kind() == reflect.Pointer {
-		if value.IsNil() {
-			return true
+
+var (
+	float32Encoder = (floatEncoder(32)).encode
+	float64Encoder = (floatEncoder(64)).encode
+)
+
+func stringEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	if opts.quoted {
+		// the ,string option quotes a string twice
+		if e.canonical {
+			return e.writeString(string(appendCanonicalString(nil, value.String())))
 		}
-	} else {
-		if value.IsZero() {
-			return true
+		e.buf = appendString(e.buf, string(appendString(nil, value.String())))
+		return nil
+	}
+	return e.writeString(value.String())
+}
+
+// writeString appends s as a JSON string,escaped minimally in canonical mode
+func (e *encodeState) writeString(s string) error {
+	if !e.canonical {
+		e.buf = appendString(e.buf, s)
+		return nil
+	}
+	if !utf8.ValidString(s) {
+		return &UnsupportedValueError{Value: reflect.ValueOf(s), Str: "invalid UTF-8 " + strconv.Quote(s)}
+	}
+	e.buf = appendCanonicalString(e.buf, s)
+	return nil
+}
+
+// interfaceEncoder looks up the encoder of the dynamic type on every call
+func interfaceEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	if value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	return e.reflectValue(value.Elem(), opts)
+}
+
+// Marshaler is the interface implemented by types that write their own JSON,it has the method set of json.Marshaler
+type Marshaler interface {
+	MarshalJSON() ([]byte, error)
+}
+
+// MarshalerError is returned when a MarshalJSON or MarshalText method fails
+type MarshalerError struct {
+	Type       reflect.Type
+	Err        error
+	sourceFunc string
+}
+
+func (e *MarshalerError) Error() string {
+	srcFunc := e.sourceFunc
+	if srcFunc == "" {
+		srcFunc = "MarshalJSON"
+	}
+	return "json: error calling " + srcFunc + " for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// marshalerEncoder calls MarshalJSON and writes its output compacted,after checking it is a single valid JSON value
+func marshalerEncoder(e *encodeState, value reflect.Value, _ encOpts) error {
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	m, ok := value.Interface().(Marshaler)
+	if !ok {
+		e.WriteString("null")
+		return nil
+	}
+	b, err := m.MarshalJSON()
+	if err == nil && e.canonical {
+		// the output of MarshalJSON is in no particular form,it is read back like Canonicalize reads and written canonically
+		var v any
+		if err = decodeInto(decodeState{data: b, useNumber: true, uniqueNames: true}, &v); err == nil {
+			return e.reflectValue(reflect.ValueOf(v), encOpts{})
 		}
 	}
-	return false
+	if err == nil {
+		e.buf, err = compact(e.buf, b)
+	}
+	if err != nil {
+		return &MarshalerError{Type: value.Type(), Err: err, sourceFunc: "MarshalJSON"}
+	}
+	return nil
+}
+
+func addrMarshalerEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	return marshalerEncoder(e, value.Addr(), opts)
+}
+
+// textMarshalerEncoder writes the output of MarshalText as a JSON string
+func textMarshalerEncoder(e *encodeState, value reflect.Value, _ encOpts) error {
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	m, ok := value.Interface().(encoding.TextMarshaler)
+	if !ok {
+		e.WriteString("null")
+		return nil
+	}
+	b, err := m.MarshalText()
+	if err != nil {
+		return &MarshalerError{Type: value.Type(), Err: err, sourceFunc: "MarshalText"}
+	}
+	return e.writeString(string(b))
 }
-func marshal(value reflect.Value) (result []byte, err error) {
-	defer func() {
-		if panicErr := recover(); panicErr != any(nil) {
-			result = nil
-			fmt.Println(panicErr)
-			err = fmt.Errorf("params analy error")
+
+func addrTextMarshalerEncoder(e *encodeState, value reflect.Value, opts encOpts) error {
+	return textMarshalerEncoder(e, value.Addr(), opts)
+}
+
+// newCondAddrEncoder uses canAddrEnc when the value is addressable,so that pointer receiver methods are found,and elseEnc otherwise
+func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
+	return func(e *encodeState, value reflect.Value, opts encOpts) error {
+		if value.CanAddr() {
+			return canAddrEnc(e, value, opts)
 		}
-	}()
-	if isInvalid(value) {
-		return nil, nil
+		return elseEnc(e, value, opts)
 	}
-	result = make([]byte, 0, value.Type().Size()*2)
-	switch value.Kind() {
-	case reflect.String:
-		result = append(result, []byte(value.String())...)
-	case reflect.Int, reflect.Int64, reflect.Int8, reflect.Int16, reflect.Int32:
-		result = append(result, []byte(strconv.FormatInt(value.Int(), 10))...)
-	case reflect.Slice:
-		var tempArr = make([]byte, 0, value.Len()*value.Type().Elem().Align())
-		for i := 0; i < value.Len(); i++ {
-			temp, err := marshal(value.Index(i))
-			if err != nil {
-				return nil, err
-			}
-			if i != value.Len()-1 {
-				temp = append(temp, ',')
+}
+
+// timeEncoder writes a time.Time as an RFC 3339 string with the same output as its MarshalJSON,without the extra copies
+func timeEncoder(e *encodeState, value reflect.Value, _ encOpts) error {
+	t := value.Interface().(time.Time)
+	if y := t.Year(); y < 0 || y >= 10000 {
+		return &MarshalerError{Type: value.Type(), Err: fmt.Errorf("Time.MarshalJSON: year outside of range [0,9999]"), sourceFunc: "MarshalJSON"}
+	}
+	e.WriteByte('"')
+	e.buf = t.AppendFormat(e.buf, time.RFC3339Nano)
+	e.WriteByte('"')
+	return nil
+}
+
+// compact appends src to dst without insignificant whitespace,src must hold exactly one JSON value.
+// '<','>','&',U+2028 and U+2029 inside strings are escaped the same way Marshal escapes its own strings.
+func compact(dst, src []byte) ([]byte, error) {
+	d := decodeState{data: src}
+	d.skipWhitespace()
+	if err := d.value(reflect.Value{}); err != nil {
+		return dst, err
+	}
+	d.skipWhitespace()
+	if d.off < len(src) {
+		return dst, d.syntaxError("invalid character " + quoteChar(src[d.off]) + " after top-level value")
+	}
+	var inString, escaped bool
+	start := 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '<' || c == '>' || c == '&':
+				dst = append(dst, src[start:i]...)
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+				start = i + 1
+			case c == 0xE2 && i+2 < len(src) && src[i+1] == 0x80 && src[i+2]&^1 == 0xA8:
+				// U+2028 and U+2029
+				dst = append(dst, src[start:i]...)
+				dst = append(dst, '\\', 'u', '2', '0', '2', hex[src[i+2]&0xF])
+				start = i + 3
+				i += 2
 			}
-			tempArr = append(tempArr, temp...)
+			continue
 		}
-		result = append(result, combineToJson(value.Type().Name(), string(tempArr), value.Kind())...)
-	case reflect.Struct:
-		var number = value.NumField()
-		var temp = make([]byte, 0, value.Type().Size())
-		for i := 0; i < number; i++ {
-			var fieldValue = value.Field(i)
-			if isInvalid(fieldValue) {
-				continue
-			}
-			fieldName := value.Type().Field(i).Name
-			bytes, err := marshal(fieldValue)
-			if err != nil {
-				return nil, err
-			}
-			fieldType := fieldValue.Kind()
-			if fieldType == reflect.Struct {
-				temp = append(temp, bytes...)
-				if i != number-1 {
-					temp = append(temp, ',')
-				}
-				continue
-			}
-			if fieldType == reflect.Map {
-				fieldType = reflect.Int
-			}
-			temp = append(temp, combineToJson(fieldName, string(bytes), fieldType)...)
-			if i != number-1 {
-				temp = append(temp, ',')
+		switch c {
+		case '"':
+			inString = true
+		case ' ', '\t', '\n', '\r':
+			dst = append(dst, src[start:i]...)
+			start = i + 1
+		}
+	}
+	return append(dst, src[start:]...), nil
+}
+
+// encodedField is a struct field with its key and encoder resolved at compile time
+type encodedField struct {
+	field
+	// the quoted name followed by ':'
+	key []byte
+	// the same with the minimal escaping of canonical mode
+	canonicalKey []byte
+	encoder      encoderFunc
+}
+
+type structEncoder struct {
+	fields []encodedField
+	// indexes of fields with the names sorted by UTF-16 code units,the member order of canonical mode
+	canonicalOrder []int
+}
+
+// newStructEncoder compiles a struct,names and options come from the json tags the way encoding/json reads them
+func newStructEncoder(t reflect.Type) encoderFunc {
+	fields := cachedTypeFields(t).list
+	se := structEncoder{fields: make([]encodedField, len(fields)), canonicalOrder: make([]int, len(fields))}
+	for i, f := range fields {
+		se.fields[i] = encodedField{
+			field:        f,
+			key:          append(appendString(nil, f.name), ':'),
+			canonicalKey: append(appendCanonicalString(nil, f.name), ':'),
+			encoder:      typeEncoder(typeByIndex(t, f.index)),
+		}
+		se.canonicalOrder[i] = i
+	}
+	sort.Slice(se.canonicalOrder, func(i, j int) bool {
+		return lessUTF16(fields[se.canonicalOrder[i]].name, fields[se.canonicalOrder[j]].name)
+	})
+	return se.encode
+}
+
+func (se structEncoder) encode(e *encodeState, value reflect.Value, _ encOpts) error {
+	if err := e.enter(); err != nil {
+		return err
+	}
+	e.WriteByte('{')
+	var first = true
+	for i := range se.fields {
+		f := &se.fields[i]
+		if e.canonical {
+			f = &se.fields[se.canonicalOrder[i]]
+		}
+		fieldValue, ok := structField(value, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		if !first {
+			if err := e.flush(); err != nil {
+				return err
 			}
+			e.WriteByte(',')
 		}
-		result = append(result, combineToJson(value.Type().Name(), string(temp), value.Kind())...)
-	case reflect.Bool:
-		result = append(result, strconv.FormatBool(value.Bool())...)
-	case reflect.Pointer, reflect.Interface, reflect.Uintptr:
-		elem := value.Elem()
-		temp, err := marshal(elem)
+		first = false
+		if e.canonical {
+			e.Write(f.canonicalKey)
+		} else {
+			e.Write(f.key)
+		}
+		if err := f.encoder(e, fieldValue, encOpts{quoted: f.quoted}); err != nil {
+			return withSegment(err, func() string { return keySegment(f.name) })
+		}
+	}
+	e.WriteByte('}')
+	e.depth--
+	return nil
+}
+
+// typeByIndex returns the declared type of the nested field at index
+func typeByIndex(t reflect.Type, index []int) reflect.Type {
+	for _, i := range index {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		t = t.Field(i).Type
+	}
+	return t
+}
+
+type mapEncoder struct {
+	// []elem,the values of one map are copied into a single slice instead of one allocation per entry
+	elemsType reflect.Type
+	elemEnc   encoderFunc
+	// the key type implements encoding.TextMarshaler,it wins over the string and integer forms
+	textKey bool
+}
+
+// newMapEncoder compiles a map,keys must be strings,integers or implement encoding.TextMarshaler
+func newMapEncoder(t reflect.Type) encoderFunc {
+	switch t.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !t.Key().Implements(textMarshalerType) {
+			return unsupportedTypeEncoder
+		}
+	}
+	me := mapEncoder{elemsType: reflect.SliceOf(t.Elem()), elemEnc: typeEncoder(t.Elem()), textKey: t.Key().Implements(textMarshalerType)}
+	return me.encode
+}
+
+type mapEntry struct {
+	key   string
+	index int
+}
+
+type mapEntries []mapEntry
+
+func (m mapEntries) Len() int           { return len(m) }
+func (m mapEntries) Less(i, j int) bool { return m[i].key < m[j].key }
+func (m mapEntries) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+// encode writes the map as an object with its keys sorted
+func (me mapEncoder) encode(e *encodeState, value reflect.Value, _ encOpts) error {
+	if value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	if err := e.enter(); err != nil {
+		return err
+	}
+	n := value.Len()
+	if n > 0 {
+		if err := e.pushPtr(value, 0); err != nil {
+			return err
+		}
+	}
+	keyValue := reflect.New(value.Type().Key()).Elem()
+	elems := reflect.MakeSlice(me.elemsType, n, n)
+	entries := make(mapEntries, 0, n)
+	iter := value.MapRange()
+	for i := 0; iter.Next(); i++ {
+		keyValue.SetIterKey(iter)
+		elems.Index(i).SetIterValue(iter)
+		key, err := resolveKeyName(keyValue, me.textKey)
 		if err != nil {
-			return nil, err
-		}
-		result = append(result, temp...)
-	case reflect.Float32, reflect.Float64:
-		result = append(result, strconv.FormatFloat(value.Float(), 'E', -1, 32)...)
-	case reflect.Complex64, reflect.Complex128:
-		result = append(result, strconv.FormatComplex(value.Complex(), 'E', -1, 32)...)
-	//maybe can improve
-	case reflect.Map:
-		keys := value.MapKeys()
-		if len(keys) <= 0 {
-			return
-		}
-		var tempArr = make([]byte, 0, 2*len(keys)*(keys[0].Type().Align())*value.MapIndex(keys[0]).Type().Align())
-		for i := 0; i < len(keys); i++ {
-			var keyValue = value.MapIndex(keys[i])
-			keyBytes, err := marshal(keys[i])
-			if err != nil {
-				return nil, err
-			}
-			valueBytes, err := marshal(keyValue)
-			if err != nil {
-				return nil, err
+			return err
+		}
+		entries = append(entries, mapEntry{key: key, index: i})
+	}
+	if e.canonical {
+		sort.Slice(entries, func(i, j int) bool { return lessUTF16(entries[i].key, entries[j].key) })
+	} else {
+		sort.Sort(entries)
+	}
+	e.WriteByte('{')
+	for i := range entries {
+		if i > 0 {
+			if err := e.flush(); err != nil {
+				return err
 			}
-			tempArr = append(tempArr, combineToJson(string(keyBytes), string(valueBytes), reflect.String)...)
+			e.WriteByte(',')
+		}
+		if err := e.writeString(entries[i].key); err != nil {
+			return err
 		}
-		result = append(result, combineToJson("", string(tempArr), reflect.Map)...)
+		e.WriteByte(':')
+		if err := me.elemEnc(e, elems.Index(entries[i].index), encOpts{}); err != nil {
+			return withSegment(err, func() string { return keySegment(entries[i].key) })
+		}
+	}
+	e.WriteByte('}')
+	if n > 0 {
+		e.popPtr()
 	}
-	return result, nil
+	e.depth--
+	return nil
 }
 
-func combineToJson(key string, value string, valueType reflect.Kind) []byte {
-	sb := strings.Builder{}
-	sb.WriteString("\"")
-	sb.WriteString(key)
-	sb.WriteString("\": ")
-	switch valueType {
+// resolveKeyName turns a map key into an object key,newMapEncoder has checked the key type
+func resolveKeyName(key reflect.Value, textKey bool) (string, error) {
+	if textKey {
+		if key.Kind() == reflect.Pointer && key.IsNil() {
+			return "", nil
+		}
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: key.Type(), Err: err, sourceFunc: "MarshalText"}
+		}
+		return string(text), nil
+	}
+	switch key.Kind() {
 	case reflect.String:
-		sb.WriteString("\"")
-		sb.WriteString(value)
-		sb.WriteString("\"")
-	case reflect.Int, reflect.Int64, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Bool:
-		sb.WriteString(value)
-	case reflect.Slice, reflect.Array:
-		sb.WriteString("[")
-		sb.WriteString(value)
-		sb.WriteString("]")
-	case reflect.Struct, reflect.Interface:
-		sb.WriteString("{")
-		sb.WriteString(value)
-		sb.WriteString("}")
-	case reflect.Map:
-		sb = strings.Builder{}
-		sb.WriteString("{")
-		sb.WriteString(value)
-		sb.WriteString("}")
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	default:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+}
+
+// newSliceEncoder compiles a slice,[]byte is written as a base64 string unless its elements marshal themselves
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	if t.Elem().Kind() == reflect.Uint8 {
+		p := reflect.PointerTo(t.Elem())
+		if !p.Implements(marshalerType) && !p.Implements(textMarshalerType) {
+			return encodeByteSlice
+		}
+	}
+	enc := newArrayEncoder(t)
+	return func(e *encodeState, value reflect.Value, opts encOpts) error {
+		if value.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		n := value.Len()
+		if n == 0 {
+			return enc(e, value, opts)
+		}
+		if err := e.pushPtr(value, n); err != nil {
+			return err
+		}
+		if err := enc(e, value, opts); err != nil {
+			return err
+		}
+		e.popPtr()
+		return nil
+	}
+}
+
+func encodeByteSlice(e *encodeState, value reflect.Value, _ encOpts) error {
+	if value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	b := value.Bytes()
+	e.WriteByte('"')
+	n := len(e.buf)
+	encodedLen := base64.StdEncoding.EncodedLen(len(b))
+	if cap(e.buf)-n < encodedLen {
+		grown := make([]byte, n, 2*cap(e.buf)+encodedLen)
+		copy(grown, e.buf)
+		e.buf = grown
+	}
+	e.buf = e.buf[:n+encodedLen]
+	base64.StdEncoding.Encode(e.buf[n:], b)
+	e.WriteByte('"')
+	return nil
+}
+
+type arrayEncoder struct {
+	elemEnc encoderFunc
+}
+
+func newArrayEncoder(t reflect.Type) encoderFunc {
+	enc := arrayEncoder{elemEnc: typeEncoder(t.Elem())}
+	return enc.encode
+}
+
+func (ae arrayEncoder) encode(e *encodeState, value reflect.Value, _ encOpts) error {
+	if err := e.enter(); err != nil {
+		return err
+	}
+	e.WriteByte('[')
+	n := value.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			if err := e.flush(); err != nil {
+				return err
+			}
+			e.WriteByte(',')
+		}
+		if err := ae.elemEnc(e, value.Index(i), encOpts{}); err != nil {
+			return withSegment(err, func() string { return indexSegment(i) })
+		}
+	}
+	e.WriteByte(']')
+	e.depth--
+	return nil
+}
+
+type ptrEncoder struct {
+	elemEnc encoderFunc
+}
+
+func newPtrEncoder(t reflect.Type) encoderFunc {
+	enc := ptrEncoder{elemEnc: typeEncoder(t.Elem())}
+	return enc.encode
+}
+
+func (pe ptrEncoder) encode(e *encodeState, value reflect.Value, opts encOpts) error {
+	if value.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	if err := e.pushPtr(value, 0); err != nil {
+		return err
+	}
+	if err := pe.elemEnc(e, value.Elem(), opts); err != nil {
+		return err
+	}
+	e.popPtr()
+	return nil
+}
+
+// appendFloat formats a float the way ES6 and encoding/json do,the shortest representation that round trips,
+// with an exponent only for very small or very large magnitudes
+func appendFloat(b []byte, value reflect.Value, bits int) ([]byte, error) {
+	f := value.Float()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, &UnsupportedValueError{Value: value, Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+const hex = "0123456789abcdef"
+
+// htmlSafe reports the ASCII characters written as they are,'<','>' and '&' are escaped so the output can be embedded in HTML
+var htmlSafe = func() (safe [utf8.RuneSelf]bool) {
+	for i := 0x20; i < utf8.RuneSelf; i++ {
+		safe[i] = i != '"' && i != '\\' && i != '<' && i != '>' && i != '&'
+	}
+	return
+}()
+
+// appendString appends s as a quoted JSON string,invalid UTF-8 is replaced by U+FFFD
+// and U+2028,U+2029 are escaped so the output is also valid JavaScript
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if htmlSafe[c] {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, string(utf8.RuneError)...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
 	}
-	sb.WriteString("\n")
-	return []byte(sb.String())
+	b = append(b, s[start:]...)
+	b = append(b, '"')
+	return b
 }
@@ -2,9 +2,16 @@ package golangUtil
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"net"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -18,9 +25,24 @@ type testStruct struct {
 	maps    map[string]interface{}
 }
 
-func TestCopy(t *testing.T) {
-	s := *JsonEncoder
-	fmt.Println(s.load(uintptr(0)))
+func TestMarshalCurrentValue(t *testing.T) {
+	type probe struct{ A int }
+	for i := 0; i < 100; i++ {
+		bytes, err := Marshal(probe{A: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf(`{"A":%d}`, i); string(bytes) != want {
+			t.Fatalf("got %s,want %s", bytes, want)
+		}
+	}
+	var value = &probe{A: 1}
+	first, _ := Marshal(value)
+	value.A = 2
+	second, _ := Marshal(value)
+	if string(first) == string(second) {
+		t.Errorf("marshal of a mutated value returned the previous output %s", second)
+	}
 }
 func TestFunctionMarsahl(t *testing.T) {
 	bytes, err := Marshal(&testStruct{name: "xiyanggou", value: "xiyangValuesagou", mapping: rand.Int(), err: fmt.Errorf("hello error"), maps: map[string]interface{}{"xiyang": testStruct{name: "xiyang"}}})
@@ -56,8 +78,6 @@ func TestMarshal(t *testing.T) {
 			return
 		}
 	}
-	fmt.Println(JsonEncoder.cacheSuccessNums)
-
 }
 func TestGolangUtilMarshal(t *testing.T) {
 	const testTimes int = 100000
@@ -81,9 +101,6 @@ func TestGolangUtilMarshal(t *testing.T) {
 	group.Wait()
 	since := time.Since(now)
 	fmt.Println(since)
-	fmt.Println(JsonEncoder.cacheNums)
-	fmt.Println(JsonEncoder.cacheSuccessNums)
-	fmt.Println(len(JsonEncoder.cache))
 }
 func TestJsonMarshal(t *testing.T) {
 	const testTimes int = 10000
@@ -123,3 +140,528 @@ func BenchmarkJSONMarshal(b *testing.B) {
 		}
 	}
 }
+
+type embeddedBase struct {
+	ID      int    `json:"id"`
+	Created string `json:"created"`
+}
+
+type EmbeddedConflict struct {
+	Created string
+}
+
+type tagFixture struct {
+	embeddedBase
+	*EmbeddedConflict
+	Renamed  string            `json:"renamed"`
+	Skipped  string            `json:"-"`
+	Dash     string            `json:"-,"`
+	Omitted  string            `json:"omitted,omitempty"`
+	Kept     string            `json:"kept"`
+	Zero     int               `json:"zero"`
+	Count    int64             `json:"count,string"`
+	Flag     bool              `json:"flag,string"`
+	Text     string            `json:"text,string"`
+	Pointer  *int              `json:"pointer"`
+	Nested   embeddedBase      `json:"nested"`
+	Empty    map[string]string `json:"empty,omitempty"`
+	NilMap   map[string]string `json:"nilMap"`
+	Untagged string
+	hidden   string
+}
+
+func TestMarshalTags(t *testing.T) {
+	var fixture = tagFixture{
+		embeddedBase: embeddedBase{ID: 7, Created: "today"},
+		Renamed:      "renamed",
+		Skipped:      "skipped",
+		Dash:         "dash",
+		Count:        42,
+		Flag:         true,
+		Text:         "text",
+		Nested:       embeddedBase{ID: 8},
+		Untagged:     "untagged",
+		hidden:       "hidden",
+	}
+	bytes, err := Marshal(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rightResult, err := json.Marshal(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bytes) != string(rightResult) {
+		t.Errorf("got  %s\nwant %s", bytes, rightResult)
+	}
+}
+
+type differentialInner struct {
+	Value float64 `json:"value"`
+	Bytes []byte  `json:"bytes,omitempty"`
+}
+
+type differentialStruct struct {
+	Name     string                        `json:"name"`
+	Inner    differentialInner             `json:"inner"`
+	Pointer  *differentialInner            `json:"pointer"`
+	List     []differentialInner           `json:"list"`
+	Array    [3]uint16                     `json:"array"`
+	Any      any                           `json:"any"`
+	ByInt    map[int]string                `json:"byInt"`
+	ByUint   map[uint8][]string            `json:"byUint"`
+	Nested   map[string]map[string]bool    `json:"nested"`
+	Nil      []int                         `json:"nil"`
+	Empty    []int                         `json:"empty"`
+	Quoted   float32                       `json:"quoted,string"`
+	Children []*differentialStruct         `json:"children,omitempty"`
+	Raw      map[string]any                `json:"raw,omitempty"`
+	Embedded struct{ A, B int }            `json:"embedded"`
+	Ptrs     map[string]*differentialInner `json:"ptrs"`
+}
+
+// differentialCorpus covers every kind Marshal supports,each entry must encode byte for byte like encoding/json
+var differentialCorpus = []any{
+	nil, true, false, 0, -1, int8(-128), int16(32767), int32(-2147483648), int64(math.MaxInt64), int64(math.MinInt64),
+	uint(0), uint8(255), uint16(65535), uint32(4294967295), uint64(math.MaxUint64), uintptr(1234),
+	0.0, math.Copysign(0, -1), 1.0, -1.5, 0.1, 1e20, 1e21, 1e-6, 1e-7, 123456789.123456789, math.MaxFloat64, math.SmallestNonzeroFloat64,
+	float32(0.1), float32(3.4028235e38), float32(1e-7), float32(16777216),
+	"", "plain", "quote\" backslash\\ slash/", "\b\f\n\r\t\x00\x1f\x7f", "<html> & 'amp'", "\u2028\u2029", "é中😀", "bad\xffutf8\xc3",
+	[]int{}, []int(nil), []int{1, 2, 3}, []string{"a", "b"}, []byte{}, []byte(nil), []byte("hello golangUtil"), [0]int{}, [2]bool{true, false},
+	[][]byte{[]byte("a"), nil}, []any{1, "a", nil, 2.5, []any{}},
+	map[string]int{}, map[string]int(nil), map[string]int{"b": 2, "a": 1, "": 0}, map[int]bool{-1: true, 10: false, 2: true},
+	map[uint64]string{18446744073709551615: "max", 0: "zero"}, map[string]any{"z": nil, "y": map[string]any{"x": []any{}}},
+	new(int), (*int)(nil), &[]string{"pointer"}, struct{}{}, &struct{}{},
+	differentialInner{Value: 0.5, Bytes: []byte{0, 255}},
+	differentialStruct{
+		Name:    "golangUtil",
+		Inner:   differentialInner{Value: 1e-9},
+		Pointer: &differentialInner{Value: -0.000001},
+		List:    []differentialInner{{Value: 1}, {Value: 2, Bytes: []byte("x")}},
+		Array:   [3]uint16{1, 2, 3},
+		Any:     map[string]any{"k": []int{1}},
+		ByInt:   map[int]string{3: "c", -3: "-c"},
+		ByUint:  map[uint8][]string{1: nil, 2: {}},
+		Nested:  map[string]map[string]bool{"a": {"b": true}},
+		Empty:   []int{},
+		Quoted:  1.25,
+		Children: []*differentialStruct{
+			{Name: "child", Any: "<b>"},
+			nil,
+		},
+		Raw:  map[string]any{"\u2028": "line separator"},
+		Ptrs: map[string]*differentialInner{"nil": nil, "set": {Value: 3}},
+	},
+	tagFixture{Renamed: "r", Count: -1, Text: "with \"quotes\" <and> tags", Pointer: new(int)},
+}
+
+func TestMarshalMatchesEncodingJson(t *testing.T) {
+	for _, value := range differentialCorpus {
+		bytes, err := Marshal(value)
+		if err != nil {
+			t.Errorf("marshal %#v: %v", value, err)
+			continue
+		}
+		rightResult, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != string(rightResult) {
+			t.Errorf("marshal %#v\n got %s\nwant %s", value, bytes, rightResult)
+		}
+		if !json.Valid(bytes) {
+			t.Errorf("invalid output %s", bytes)
+		}
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	var unsupportedValues = []any{math.NaN(), math.Inf(1), float32(math.Inf(-1)), []float64{1, math.NaN()}, map[string]any{"nan": math.NaN()}}
+	for _, value := range unsupportedValues {
+		var valueErr *UnsupportedValueError
+		if _, err := Marshal(value); !errors.As(err, &valueErr) {
+			t.Errorf("marshal %v: want UnsupportedValueError,got %v", value, err)
+		}
+	}
+	var unsupportedTypes = []any{make(chan int), func() {}, complex(1, 2), map[bool]int{true: 1}, struct{ C chan int }{}}
+	for _, value := range unsupportedTypes {
+		var typeErr *UnsupportedTypeError
+		if _, err := Marshal(value); !errors.As(err, &typeErr) {
+			t.Errorf("marshal %T: want UnsupportedTypeError,got %v", value, err)
+		}
+		if _, err := json.Marshal(value); err == nil {
+			t.Errorf("encoding/json accepts %T", value)
+		}
+	}
+}
+
+type benchAddress struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	Zip     string `json:"zip"`
+	Country string `json:"country"`
+}
+
+type benchItem struct {
+	SKU       string            `json:"sku"`
+	Name      string            `json:"name"`
+	Quantity  int               `json:"quantity"`
+	UnitPrice float64           `json:"unitPrice"`
+	Discount  *float64          `json:"discount,omitempty"`
+	Tags      []string          `json:"tags"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+type benchCustomer struct {
+	ID        int64          `json:"id,string"`
+	Email     string         `json:"email"`
+	Name      string         `json:"name"`
+	Addresses []benchAddress `json:"addresses"`
+	VIP       bool           `json:"vip"`
+}
+
+type benchOrder struct {
+	OrderID  string            `json:"orderId"`
+	Customer *benchCustomer    `json:"customer"`
+	Items    []benchItem       `json:"items"`
+	Shipping benchAddress      `json:"shipping"`
+	Total    float64           `json:"total"`
+	Currency string            `json:"currency"`
+	Status   string            `json:"status"`
+	Notes    []string          `json:"notes,omitempty"`
+	Metadata map[string]any    `json:"metadata"`
+	History  []map[string]any  `json:"history"`
+	Related  []*benchOrder     `json:"related,omitempty"`
+	Flags    map[string]bool   `json:"flags"`
+	Headers  map[string]string `json:"-"`
+}
+
+func newBenchOrder() *benchOrder {
+	discount := 0.15
+	address := benchAddress{Street: "1 Infinite Loop", City: "Cupertino", Zip: "95014", Country: "US"}
+	order := &benchOrder{
+		OrderID: "ord_8c2f4a1e",
+		Customer: &benchCustomer{
+			ID:        5577006791947779410,
+			Email:     "xiyang@example.com",
+			Name:      "xiyang <gou>",
+			Addresses: []benchAddress{address, {Street: "2 Main St", City: "Springfield", Zip: "12345", Country: "US"}},
+			VIP:       true,
+		},
+		Shipping: address,
+		Total:    1234.56,
+		Currency: "USD",
+		Status:   "paid",
+		Metadata: map[string]any{"source": "web", "campaign": "spring", "attempts": 2, "ab": []any{"a", "b"}},
+		History: []map[string]any{
+			{"status": "created", "at": "2023-03-01T10:00:00Z"},
+			{"status": "paid", "at": "2023-03-01T10:05:00Z", "amount": 1234.56},
+		},
+		Flags: map[string]bool{"gift": false, "express": true},
+	}
+	for i := 0; i < 10; i++ {
+		order.Items = append(order.Items, benchItem{
+			SKU:       "sku-" + strconv.Itoa(i),
+			Name:      "item \"" + strconv.Itoa(i) + "\"",
+			Quantity:  i + 1,
+			UnitPrice: 9.99 * float64(i+1),
+			Discount:  &discount,
+			Tags:      []string{"tag-a", "tag-b", "tag-c"},
+			Attrs:     map[string]string{"color": "red", "size": "M"},
+		})
+	}
+	order.Related = []*benchOrder{{OrderID: "ord_related", Items: order.Items[:2], Metadata: map[string]any{}}}
+	return order
+}
+
+func TestMarshalNestedMatchesEncodingJson(t *testing.T) {
+	order := newBenchOrder()
+	bytes, err := Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rightResult, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bytes) != string(rightResult) {
+		t.Errorf("got  %s\nwant %s", bytes, rightResult)
+	}
+}
+
+type recursiveNode struct {
+	Name     string           `json:"name"`
+	Children []*recursiveNode `json:"children,omitempty"`
+	Index    map[string]*recursiveNode
+}
+
+func TestMarshalConcurrentTypeCompile(t *testing.T) {
+	node := &recursiveNode{Name: "root", Children: []*recursiveNode{{Name: "a"}, {Name: "b", Children: []*recursiveNode{{Name: "c"}}}}}
+	node.Index = map[string]*recursiveNode{"a": node.Children[0]}
+	rightResult, err := json.Marshal(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := sync.WaitGroup{}
+	for i := 0; i < 64; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			bytes, err := Marshal(node)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(bytes) != string(rightResult) {
+				t.Errorf("got  %s\nwant %s", bytes, rightResult)
+			}
+		}()
+	}
+	group.Wait()
+}
+
+func BenchmarkGolangUtilMarshalNested(b *testing.B) {
+	order := newBenchOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONMarshalNested(b *testing.B) {
+	order := newBenchOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGolangUtilMarshalNestedParallel(b *testing.B) {
+	order := newBenchOrder()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := Marshal(order); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkJSONMarshalNestedParallel(b *testing.B) {
+	order := newBenchOrder()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := json.Marshal(order); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// celsius marshals itself with a value receiver
+type celsius float64
+
+func (c celsius) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{ "celsius" : %g }`, float64(c))), nil
+}
+
+func (c *celsius) UnmarshalJSON(data []byte) error {
+	var v struct{ Celsius float64 }
+	if err := Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = celsius(v.Celsius)
+	return nil
+}
+
+// level marshals itself with a pointer receiver,only addressable values use it
+type level int
+
+func (l *level) MarshalText() ([]byte, error) {
+	return []byte("L" + strconv.Itoa(int(*l))), nil
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	n, err := strconv.Atoi(strings.TrimPrefix(string(text), "L"))
+	*l = level(n)
+	return err
+}
+
+// colour is a TextMarshaler map key
+type colour string
+
+func (c colour) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(c))), nil
+}
+
+func (c *colour) UnmarshalText(text []byte) error {
+	*c = colour(strings.ToLower(string(text)))
+	return nil
+}
+
+type brokenMarshaler struct{}
+
+func (brokenMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":`), nil
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+type marshalerFixture struct {
+	Created  time.Time
+	Updated  *time.Time
+	Temp     celsius
+	TempPtr  *celsius
+	Level    level
+	Levels   []level
+	Colours  map[colour]int
+	IP       net.IP
+	Big      *big.Int
+	Duration time.Duration
+	Raw      json.RawMessage
+}
+
+func TestMarshalMarshalers(t *testing.T) {
+	updated := time.Date(2024, 2, 29, 23, 59, 59, 123456789, time.FixedZone("X", 3600))
+	temp := celsius(21.5)
+	fixture := marshalerFixture{
+		Created:  time.Date(2001, 1, 2, 3, 4, 5, 0, time.UTC),
+		Updated:  &updated,
+		Temp:     -3.25,
+		TempPtr:  &temp,
+		Level:    4,
+		Levels:   []level{1, 2},
+		Colours:  map[colour]int{"red": 1, "green": 2},
+		IP:       net.ParseIP("10.0.0.1"),
+		Big:      new(big.Int).Lsh(big.NewInt(1), 80),
+		Duration: time.Second,
+		Raw:      json.RawMessage(` [1, "<b>"] `),
+	}
+	// the struct by value isn't addressable,a pointer makes the pointer receivers reachable
+	for _, value := range []any{fixture, &fixture, []marshalerFixture{fixture}, map[string]any{"f": fixture}, time.Time{}, level(3)} {
+		bytes, err := Marshal(value)
+		if err != nil {
+			t.Fatalf("marshal %T: %v", value, err)
+		}
+		rightResult, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != string(rightResult) {
+			t.Errorf("marshal %T\n got %s\nwant %s", value, bytes, rightResult)
+		}
+	}
+}
+
+func TestMarshalMarshalerErrors(t *testing.T) {
+	var marshalerErr *MarshalerError
+	if _, err := Marshal(failingMarshaler{}); !errors.As(err, &marshalerErr) || err.Error() != "json: error calling MarshalJSON for type golangUtil.failingMarshaler: boom" {
+		t.Errorf("want MarshalerError,got %v", err)
+	}
+	if _, err := Marshal(brokenMarshaler{}); !errors.As(err, &marshalerErr) {
+		t.Errorf("invalid MarshalJSON output: want MarshalerError,got %v", err)
+	}
+	if _, err := Marshal(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.As(err, &marshalerErr) {
+		t.Errorf("year 10000: want MarshalerError,got %v", err)
+	}
+}
+
+type treeNode struct {
+	Name     string
+	Parent   *treeNode `json:",omitempty"`
+	Children []*treeNode
+}
+
+func TestMarshalCycles(t *testing.T) {
+	root := &treeNode{Name: "root"}
+	for i := 0; i < 5; i++ {
+		root.Children = append(root.Children, &treeNode{Name: strconv.Itoa(i)})
+	}
+	// a node shared by two parents is not a cycle
+	root.Children[1].Children = []*treeNode{root.Children[0]}
+	if _, err := Marshal(root); err != nil {
+		t.Fatalf("shared node: %v", err)
+	}
+	root.Children[3].Parent = root
+	var cycleErr *CycleError
+	_, err := Marshal(root)
+	if !errors.As(err, &cycleErr) || cycleErr.Path != "$.Children[3].Parent" || cycleErr.Type != reflect.TypeOf(root) {
+		t.Fatalf("want a cycle at $.Children[3].Parent,got %v", err)
+	}
+	if err.Error() != "json: encountered a cycle via *golangUtil.treeNode at $.Children[3].Parent" {
+		t.Errorf("message %q", err.Error())
+	}
+
+	self := map[string]any{"a b": nil}
+	self["a b"] = []any{1, self}
+	if _, err := Marshal(self); !errors.As(err, &cycleErr) || cycleErr.Path != `$["a b"][1]` {
+		t.Errorf("want a cycle at $[\"a b\"][1],got %v", err)
+	}
+	list := []any{nil}
+	list[0] = list
+	if _, err := Marshal(list); !errors.As(err, &cycleErr) || cycleErr.Path != "$[0]" {
+		t.Errorf("want a cycle at $[0],got %v", err)
+	}
+
+	// a long chain is indexed by a map instead of scanned,both must find the cycle
+	head := &treeNode{Name: "0"}
+	tail := head
+	for i := 1; i < 3*ptrScanLimit; i++ {
+		tail.Children = []*treeNode{{Name: strconv.Itoa(i)}}
+		tail = tail.Children[0]
+	}
+	bytes, err := Marshal(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rightResult, _ := json.Marshal(head); string(bytes) != string(rightResult) {
+		t.Errorf("deep chain differs from encoding/json")
+	}
+	tail.Parent = head
+	if _, err := Marshal(head); !errors.As(err, &cycleErr) || !strings.HasSuffix(cycleErr.Path, ".Children[0].Parent") {
+		t.Errorf("want a cycle at the tail,got %v", err)
+	}
+	// the pooled state of a failed encoding doesn't leak into the next one
+	if _, err := Marshal(root.Children[0]); err != nil {
+		t.Errorf("marshal after a cycle: %v", err)
+	}
+}
+
+func TestMarshalMaxDepth(t *testing.T) {
+	var deep any = 1
+	for i := 0; i < DefaultMaxDepth+1; i++ {
+		deep = []any{deep}
+	}
+	var depthErr *DepthError
+	if _, err := Marshal(deep); !errors.As(err, &depthErr) || depthErr.MaxDepth != DefaultMaxDepth {
+		t.Fatalf("want DepthError,got %v", err)
+	}
+	if want := "$" + strings.Repeat("[0]", DefaultMaxDepth); depthErr.Path != want {
+		t.Errorf("path of %d bytes,want %d", len(depthErr.Path), len(want))
+	}
+
+	var buf strings.Builder
+	enc := NewEncoder(&buf)
+	enc.SetMaxDepth(3)
+	if err := enc.Encode(map[string]any{"a": []any{1, 2}}); err != nil {
+		t.Errorf("depth 3: %v", err)
+	}
+	err := enc.Encode(map[string]any{"a": []any{1, struct{ B []int }{B: []int{1}}}})
+	if !errors.As(err, &depthErr) || depthErr.Path != "$.a[1].B" || err.Error() != "json: exceeded max depth 3 at $.a[1].B" {
+		t.Errorf("want DepthError at $.a[1].B,got %v", err)
+	}
+	enc.SetMaxDepth(0)
+	if err := enc.Encode(deep.([]any)[0].([]any)[0]); err != nil {
+		t.Errorf("default depth: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package golangUtil
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys is the number of keys a keyedLimiter tracks when WithMaxKeys isn't given
+const DefaultMaxKeys = 10000
+
+// DefaultKeyTTL is how long the limiter of an idle key is kept when WithKeyTTL isn't given
+const DefaultKeyTTL = 10 * time.Minute
+
+// KeyFunc derives the key a request is limited under,requests without a key share the limiter of ""
+type KeyFunc func(request *http.Request) string
+
+// LimiterFactory builds the limiter of a key the first time the key is seen
+type LimiterFactory func() Limiter
+
+// KeyByIP keys requests by the IP of the client,taken from the remote address of the connection.
+// behind a proxy use KeyByHeader with the header the proxy sets
+func KeyByIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader keys requests by the value of a header such as X-API-Key
+func KeyByHeader(name string) KeyFunc {
+	return func(request *http.Request) string {
+		return request.Header.Get(name)
+	}
+}
+
+// KeyByRoute keys requests by the first route template their path matches,so that /users/1 and /users/2 share /users/:id.
+// a segment starting with ':' or wrapped in '{}' matches any one segment and a last segment "*" matches the rest of the path,
+// paths matching no template share the key ""
+func KeyByRoute(templates ...string) KeyFunc {
+	routes := make([][]string, len(templates))
+	for i, template := range templates {
+		routes[i] = strings.Split(strings.Trim(template, "/"), "/")
+	}
+	return func(request *http.Request) string {
+		segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+		for i, route := range routes {
+			if matchRoute(route, segments) {
+				return templates[i]
+			}
+		}
+		return ""
+	}
+}
+
+func matchRoute(route, segments []string) bool {
+	for i, part := range route {
+		if part == "*" && i == len(route)-1 {
+			return true
+		}
+		if i == len(segments) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return len(route) == len(segments)
+}
+
+// keyedLimiter keeps a limiter per key so that a noisy client only uses up its own quota.
+// the map is bounded: keys idle for longer than the ttl are dropped and start again with a fresh limiter,
+// and while maxKeys keys are tracked the new keys share the overflow limiter,so a tracked key never loses what it used
+type keyedLimiter struct {
+	keyFunc    KeyFunc
+	newLimiter LimiterFactory
+	maxKeys    int
+	// the limiter of the keys past maxKeys,built on first use
+	newOverflow LimiterFactory
+	overflow    Limiter
+	ttl        time.Duration
+	// tier factories by name and the tier of each key listed in the tier table
+	tiers     map[string]LimiterFactory
+	tierTable map[string]string
+	clock     Clock
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	// keys by last use,the front is the most recent
+	order *list.List
+}
+
+type keyedEntry struct {
+	key      string
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+type keyedLimiterOptions func(limiter *keyedLimiter)
+
+// WithKeyFunc sets how the key of a request is derived,KeyByIP by default
+func WithKeyFunc(keyFunc KeyFunc) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.keyFunc = keyFunc
+	}
+}
+
+// WithKeyLimiter sets the limiter built for keys without a tier,a slide windows limiter with the default options by default.
+// the sub windows of a slide windows limiter are only allocated as permits are taken,a key seen once costs a few hundred bytes
+func WithKeyLimiter(factory LimiterFactory) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.newLimiter = factory
+	}
+}
+
+// WithMaxKeys bounds the number of keys tracked at once
+func WithMaxKeys(numbers int) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.maxKeys = numbers
+	}
+}
+
+// WithOverflowLimiter sets the limiter shared by the new keys while WithMaxKeys keys are tracked,
+// the limiter of WithKeyLimiter by default
+func WithOverflowLimiter(factory LimiterFactory) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.newOverflow = factory
+	}
+}
+
+// WithKeyTTL sets how long the limiter of an idle key is kept
+func WithKeyTTL(ttl time.Duration) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.ttl = ttl
+	}
+}
+
+// WithKeyClock sets the clock the ttl of the keys is measured with
+func WithKeyClock(clock Clock) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.clock = clock
+	}
+}
+
+// WithTier registers the limiter of a tier,e.g. WithTier("gold", func() Limiter { return NewTokenBucketLimiter(100, 200) })
+func WithTier(name string, factory LimiterFactory) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		limiter.tiers[name] = factory
+	}
+}
+
+// WithTierTable assigns keys to tiers registered with WithTier,keys missing from the table or naming an unknown tier get the default limiter
+func WithTierTable(table map[string]string) keyedLimiterOptions {
+	return func(limiter *keyedLimiter) {
+		for key, tier := range table {
+			limiter.tierTable[key] = tier
+		}
+	}
+}
+
+// NewKeyedLimiter returns a limiter keeping one limiter per key,keyed by client IP unless options say otherwise
+func NewKeyedLimiter(options ...keyedLimiterOptions) *keyedLimiter {
+	result := &keyedLimiter{
+		keyFunc: KeyByIP,
+		newLimiter: func() Limiter {
+			return NewSlideWindowsLimiter()
+		},
+		maxKeys:   DefaultMaxKeys,
+		ttl:       DefaultKeyTTL,
+		tiers:     make(map[string]LimiterFactory),
+		tierTable: make(map[string]string),
+		clock:     SystemClock,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	if result.maxKeys < 1 {
+		result.maxKeys = 1
+	}
+	if result.newOverflow == nil {
+		result.newOverflow = result.newLimiter
+	}
+	return result
+}
+
+// Limiter returns the limiter of key,building it on first use,
+// a new key gets the shared overflow limiter while maxKeys keys are tracked
+func (k *keyedLimiter) Limiter(key string) Limiter {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	now := k.clock.Now()
+	k.expire(now)
+	if element, ok := k.entries[key]; ok {
+		entry := element.Value.(*keyedEntry)
+		entry.lastUsed = now
+		k.order.MoveToFront(element)
+		return entry.limiter
+	}
+	if k.order.Len() >= k.maxKeys {
+		if k.overflow == nil {
+			k.overflow = k.newOverflow()
+		}
+		return k.overflow
+	}
+	entry := &keyedEntry{key: key, limiter: k.factory(key)(), lastUsed: now}
+	k.entries[key] = k.order.PushFront(entry)
+	return entry.limiter
+}
+
+// Key returns the key of request
+func (k *keyedLimiter) Key(request *http.Request) string {
+	return k.keyFunc(request)
+}
+
+// Allow reports whether request may go through the limiter of its key
+func (k *keyedLimiter) Allow(request *http.Request) bool {
+	return k.Limiter(k.Key(request)).Allow()
+}
+
+// Len returns the number of keys tracked,the keys on the overflow limiter aren't
+func (k *keyedLimiter) Len() int {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.expire(k.clock.Now())
+	return k.order.Len()
+}
+
+// Remove drops the limiter of key,its next request starts with a fresh quota
+func (k *keyedLimiter) Remove(key string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if element, ok := k.entries[key]; ok {
+		k.remove(element)
+	}
+}
+
+func (k *keyedLimiter) factory(key string) LimiterFactory {
+	if tier, ok := k.tierTable[key]; ok {
+		if factory, ok := k.tiers[tier]; ok {
+			return factory
+		}
+	}
+	return k.newLimiter
+}
+
+// expire drops the keys idle for longer than the ttl,they sit at the back of the order
+func (k *keyedLimiter) expire(now time.Time) {
+	if k.ttl <= 0 {
+		return
+	}
+	for element := k.order.Back(); element != nil; element = k.order.Back() {
+		if now.Sub(element.Value.(*keyedEntry).lastUsed) <= k.ttl {
+			return
+		}
+		k.remove(element)
+	}
+}
+
+func (k *keyedLimiter) remove(element *list.Element) {
+	k.order.Remove(element)
+	delete(k.entries, element.Value.(*keyedEntry).key)
+}
+
+// RegistryKeyedLimiter returns a middleware admitting each request through the limiter of its key,
+// refused requests are answered like RegistryLimiter answers them
+func RegistryKeyedLimiter(limiter *keyedLimiter, options ...rateLimitMiddlewareOptions) MiddleWare {
+	middleware := newRateLimitMiddleware(options)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			middleware.serve(limiter.Limiter(limiter.Key(request)), next, writer, request)
+		},
+		)
+	}
+}
+
+// RegistryKeyedRateLimiting is RegistryRateLimiting with one limiter per key instead of one for every request
+func RegistryKeyedRateLimiting(options ...keyedLimiterOptions) MiddleWare {
+	return RegistryKeyedLimiter(NewKeyedLimiter(options...))
+}
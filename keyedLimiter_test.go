@@ -0,0 +1,127 @@
+package golangUtil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	request := httptest.NewRequest("GET", "/users/42/orders", nil)
+	request.RemoteAddr = "10.0.0.7:51234"
+	request.Header.Set("X-API-Key", "acme")
+	if key := KeyByIP(request); key != "10.0.0.7" {
+		t.Fatalf("KeyByIP = %q", key)
+	}
+	if key := KeyByHeader("X-API-Key")(request); key != "acme" {
+		t.Fatalf("KeyByHeader = %q", key)
+	}
+	routes := KeyByRoute("/users/:id", "/users/{id}/orders", "/static/*")
+	for path, want := range map[string]string{
+		"/users/42/orders":  "/users/{id}/orders",
+		"/users/7":          "/users/:id",
+		"/static/css/a.css": "/static/*",
+		"/other":            "",
+	} {
+		if key := routes(httptest.NewRequest("GET", path, nil)); key != want {
+			t.Errorf("KeyByRoute(%s) = %q,want %q", path, key, want)
+		}
+	}
+}
+
+func TestKeyedLimiterIsolatesKeys(t *testing.T) {
+	limiter := NewKeyedLimiter(WithKeyFunc(KeyByHeader("X-API-Key")), WithKeyLimiter(func() Limiter {
+		return NewTokenBucketLimiter(1, 2)
+	}))
+	handler := RegistryKeyedLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(key string) int {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	for i := 0; i < 2; i++ {
+		if code := serve("noisy"); code != 200 {
+			t.Fatalf("request %d of noisy = %d", i, code)
+		}
+	}
+	if code := serve("noisy"); code != 429 {
+		t.Fatalf("noisy past its quota = %d", code)
+	}
+	if code := serve("quiet"); code != 200 {
+		t.Fatalf("quiet starved by noisy = %d", code)
+	}
+}
+
+func TestKeyedLimiterTiers(t *testing.T) {
+	limiter := NewKeyedLimiter(
+		WithKeyLimiter(func() Limiter { return NewTokenBucketLimiter(1, 1) }),
+		WithTier("gold", func() Limiter { return NewTokenBucketLimiter(1, 5) }),
+		WithTierTable(map[string]string{"acme": "gold", "typo": "platinum"}),
+	)
+	if !limiter.Limiter("acme").AllowN(5) {
+		t.Fatal("gold key didn't get the gold quota")
+	}
+	if limiter.Limiter("other").AllowN(2) || limiter.Limiter("typo").AllowN(2) {
+		t.Fatal("key without a known tier got more than the default quota")
+	}
+}
+
+func TestKeyedLimiterBounds(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewKeyedLimiter(WithMaxKeys(2), WithKeyTTL(time.Minute), WithKeyClock(clock))
+	a := limiter.Limiter("a")
+	b := limiter.Limiter("b")
+	if limiter.Limiter("a") != a {
+		t.Fatal("limiter of a live key rebuilt")
+	}
+	// the map is full,new keys share the overflow limiter and the tracked ones keep theirs
+	c := limiter.Limiter("c")
+	if c == a || c == b || limiter.Limiter("d") != c {
+		t.Fatal("new keys past the bound don't share the overflow limiter")
+	}
+	if limiter.Len() != 2 || limiter.Limiter("a") != a || limiter.Limiter("b") != b {
+		t.Fatalf("a new key dropped a tracked one,%d keys", limiter.Len())
+	}
+	clock.Advance(time.Minute)
+	if limiter.Len() != 2 {
+		t.Fatal("keys dropped at the ttl")
+	}
+	clock.Advance(time.Nanosecond)
+	if n := limiter.Len(); n != 0 {
+		t.Fatalf("%d keys left after the ttl", n)
+	}
+	if limiter.Limiter("c") == c {
+		t.Fatal("key kept the overflow limiter once the map had room")
+	}
+	limiter.Remove("c")
+	limiter.Limiter("a")
+	limiter.Remove("a")
+	if limiter.Len() != 0 {
+		t.Fatal("Remove kept the key")
+	}
+}
+
+// a client changing its key on every request makes the limiter hold one limiter per request,each must stay small
+func TestKeyedLimiterMemoryPerKey(t *testing.T) {
+	const keys = 2000
+	limiter := NewKeyedLimiter(WithMaxKeys(keys))
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for i := 0; i < keys; i++ {
+		limiter.Limiter(strconv.Itoa(i)).Allow()
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(limiter)
+	if perKey := (int64(after.HeapAlloc) - int64(before.HeapAlloc)) / keys; perKey > 2048 {
+		t.Fatalf("%d bytes per key", perKey)
+	} else {
+		t.Logf("%d bytes per key", perKey)
+	}
+}
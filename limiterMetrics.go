@@ -0,0 +1,96 @@
+package golangUtil
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// LimiterMetrics is what a limiter decided so far and how much of its quota is in use now
+type LimiterMetrics struct {
+	Name string
+	// events admitted and refused since the limiter was observed
+	Allowed  uint64
+	Rejected uint64
+	// share of the quota in use,from 0 to 1,0 for a limiter that can't tell its quota
+	Utilization float64
+}
+
+// MetricsHook receives the metrics of a limiter after each of its decisions,
+// it runs on the path of the request so it should only update counters and gauges
+type MetricsHook func(metrics LimiterMetrics)
+
+// observedLimiter counts the decisions of a limiter,so that throttling can be alerted on before users notice it
+type observedLimiter struct {
+	name     string
+	limiter  Limiter
+	hook     MetricsHook
+	allowed  uint64
+	rejected uint64
+}
+
+// NewObservedLimiter wraps limiter to count its decisions under name,hook may be nil when Metrics is polled instead.
+// the wrapper is a Limiter itself and can be given to any middleware
+func NewObservedLimiter(name string, limiter Limiter, hook MetricsHook) *observedLimiter {
+	return &observedLimiter{name: name, limiter: limiter, hook: hook}
+}
+
+// Metrics returns the counts and the utilization of the limiter now
+func (o *observedLimiter) Metrics() LimiterMetrics {
+	metrics := LimiterMetrics{
+		Name:     o.name,
+		Allowed:  atomic.LoadUint64(&o.allowed),
+		Rejected: atomic.LoadUint64(&o.rejected),
+	}
+	if stater, ok := o.limiter.(stateLimiter); ok {
+		if state := stater.State(); state.Limit > 0 {
+			metrics.Utilization = 1 - float64(state.Remaining)/float64(state.Limit)
+		} else if state.RetryAfter > 0 {
+			// a limit of 0 admits nothing,the zero state of a limiter that can't tell has no RetryAfter
+			metrics.Utilization = 1
+		}
+	}
+	return metrics
+}
+
+func (o *observedLimiter) record(allowed bool, n int64) {
+	if allowed {
+		atomic.AddUint64(&o.allowed, uint64(n))
+	} else {
+		atomic.AddUint64(&o.rejected, uint64(n))
+	}
+	if o.hook != nil {
+		o.hook(o.Metrics())
+	}
+}
+
+func (o *observedLimiter) Allow() bool {
+	return o.AllowN(1)
+}
+
+func (o *observedLimiter) AllowN(n int64) bool {
+	allowed := o.limiter.AllowN(n)
+	o.record(allowed, n)
+	return allowed
+}
+
+// Reserve counts a reservation the limiter can grant as allowed,whatever its delay
+func (o *observedLimiter) Reserve() *Reservation {
+	r := o.limiter.Reserve()
+	o.record(r.OK(), 1)
+	return r
+}
+
+// Wait counts a wait that ends with an error as rejected
+func (o *observedLimiter) Wait(ctx context.Context) error {
+	err := o.limiter.Wait(ctx)
+	o.record(err == nil, 1)
+	return err
+}
+
+// State returns the quota of the wrapped limiter,the zero value when it can't tell
+func (o *observedLimiter) State() RateLimitState {
+	if stater, ok := o.limiter.(stateLimiter); ok {
+		return stater.State()
+	}
+	return RateLimitState{}
+}
@@ -0,0 +1,54 @@
+package golangUtil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObservedLimiter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var last LimiterMetrics
+	calls := 0
+	limiter := NewObservedLimiter("api", NewTokenBucketLimiter(1, 4, WithBucketClock(clock)), func(metrics LimiterMetrics) {
+		last = metrics
+		calls++
+	})
+	limiter.Allow()
+	if last.Name != "api" || last.Allowed != 1 || last.Utilization != 0.25 {
+		t.Fatalf("metrics after one event = %+v", last)
+	}
+	limiter.AllowN(2)
+	limiter.AllowN(2)
+	limiter.Reserve()
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := limiter.Wait(short); err != ErrExceedsDeadline {
+		t.Fatalf("Wait past the deadline = %v", err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Wait(canceled)
+	metrics := limiter.Metrics()
+	if calls != 6 || metrics.Allowed != 4 || metrics.Rejected != 4 || metrics.Utilization != 1 {
+		t.Fatalf("%d calls,metrics = %+v", calls, metrics)
+	}
+}
+
+func TestObservedLimiterMiddleware(t *testing.T) {
+	limiter := NewObservedLimiter("api", NewGCRALimiter(1, 1), nil)
+	handler := RegistryLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if metrics := limiter.Metrics(); metrics.Allowed != 1 || metrics.Rejected != 3 {
+		t.Fatalf("metrics = %+v", metrics)
+	}
+	if recorder.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatal("observed limiter hides the state of the limiter from the middleware")
+	}
+}
@@ -0,0 +1,348 @@
+package golangUtil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaSaveInterval is how often a limiter with a store saves its persisted tiers
+const DefaultQuotaSaveInterval = time.Second
+
+// Quota is one tier of a quotaLimiter: Limit events per Period,e.g. 500 per minute
+type Quota struct {
+	Name   string
+	Limit  int64
+	Period time.Duration
+	// Persist keeps the tier in the store of WithQuotaStore,so that it survives restarts
+	Persist bool
+}
+
+// QuotaWindow is the count of a tier in the window starting at Start
+type QuotaWindow struct {
+	Start time.Time
+	Count int64
+}
+
+// QuotaStore keeps the window of the persisted tiers across restarts,key names the limiter and the tier.
+// Save is called in the background every save interval and by Flush,never on the path of a request
+type QuotaStore interface {
+	// Load returns the saved window of key,ok is false when nothing was saved
+	Load(key string) (window QuotaWindow, ok bool, err error)
+	Save(key string, window QuotaWindow) error
+}
+
+// QuotaDecision is the outcome of TakeN
+type QuotaDecision struct {
+	Allowed bool
+	// the tier refusing the events,the one refusing them longest when several do
+	Tier string
+	// how long until every tier would admit the events,0 when they can never fit a tier
+	RetryAfter time.Duration
+}
+
+// quotaTier counts events in fixed windows of its period aligned on the zero time,e.g. UTC days,
+// so that no window ever holds more than Limit events.
+// it keeps the window of now and the later ones reservations booked events in
+type quotaTier struct {
+	Quota
+	// events by window start in unix nanoseconds
+	windows map[int64]int64
+	// changed since the last save
+	dirty bool
+}
+
+// quotaLimiter checks several quotas at once,e.g. 10/s,500/min and 50k/day.
+// events are admitted only when every tier admits them and then taken from every tier,
+// so a tier refusing a request never leaves the others charged for it
+type quotaLimiter struct {
+	lock  sync.Mutex
+	tiers []*quotaTier
+	clock Clock
+	store QuotaStore
+	key   string
+	// the last error of the store
+	err error
+
+	saveInterval time.Duration
+	// keeps the saves in order
+	saving    sync.Mutex
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+type quotaLimiterOptions func(limiter *quotaLimiter)
+
+// WithQuotaStore persists the tiers with Persist set in store under key plus the name of the tier,
+// limiters of different callers need different keys
+func WithQuotaStore(store QuotaStore, key string) quotaLimiterOptions {
+	return func(limiter *quotaLimiter) {
+		limiter.store = store
+		limiter.key = key
+	}
+}
+
+// WithQuotaSaveInterval sets how often the persisted tiers are saved,DefaultQuotaSaveInterval by default
+func WithQuotaSaveInterval(interval time.Duration) quotaLimiterOptions {
+	return func(limiter *quotaLimiter) {
+		limiter.saveInterval = interval
+	}
+}
+
+// WithQuotaClock sets the clock of the limiter
+func WithQuotaClock(clock Clock) quotaLimiterOptions {
+	return func(limiter *quotaLimiter) {
+		limiter.clock = clock
+	}
+}
+
+// NewQuotaLimiter returns a limiter enforcing every quota,each one admits at most Limit events per window of its Period.
+// the persisted tiers start from the store,a failing store leaves them fresh and is reported by Err.
+// with a store the limiter saves in the background until Close
+func NewQuotaLimiter(quotas []Quota, options ...quotaLimiterOptions) *quotaLimiter {
+	if len(quotas) == 0 {
+		panic(any("quota limiter needs at least one quota"))
+	}
+	result := &quotaLimiter{clock: SystemClock, saveInterval: DefaultQuotaSaveInterval, closing: make(chan struct{})}
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	if result.saveInterval <= 0 {
+		panic(any("quota save interval must be positive"))
+	}
+	for _, quota := range quotas {
+		if quota.Limit < 1 || quota.Period <= 0 {
+			panic(any("quota needs a positive limit and period"))
+		}
+		tier := &quotaTier{Quota: quota, windows: map[int64]int64{}}
+		if quota.Persist && result.store != nil {
+			window, ok, err := result.store.Load(result.storeKey(tier))
+			if err != nil {
+				result.err = err
+			} else if ok {
+				tier.windows[tier.window(window.Start)] = window.Count
+			}
+		}
+		result.tiers = append(result.tiers, tier)
+	}
+	if result.store != nil {
+		go result.saveLoop()
+	}
+	return result
+}
+
+func (l *quotaLimiter) storeKey(tier *quotaTier) string {
+	return l.key + ":" + tier.Name
+}
+
+// window returns the start of the window holding at
+func (t *quotaTier) window(at time.Time) int64 {
+	return at.Truncate(t.Period).UnixNano()
+}
+
+// expire drops the windows ended before now
+func (t *quotaTier) expire(now time.Time) {
+	current := t.window(now)
+	for start := range t.windows {
+		if start < current {
+			delete(t.windows, start)
+		}
+	}
+}
+
+// fit returns the first time from at whose window holds n more events,n must not exceed Limit
+func (t *quotaTier) fit(at time.Time, n int64) time.Time {
+	for t.windows[t.window(at)]+n > t.Limit {
+		at = at.Truncate(t.Period).Add(t.Period)
+	}
+	return at
+}
+
+// fit returns the first time from now every tier admits n events at,the lock must be held
+func (l *quotaLimiter) fit(now time.Time, n int64) time.Time {
+	at := now
+	for moved := true; moved; {
+		moved = false
+		for _, tier := range l.tiers {
+			if next := tier.fit(at, n); next.After(at) {
+				at, moved = next, true
+			}
+		}
+	}
+	return at
+}
+
+// book adds n events,negative to refund them,to every tier in the windows holding at,the lock must be held
+func (l *quotaLimiter) book(at time.Time, n int64) {
+	for _, tier := range l.tiers {
+		start := tier.window(at)
+		if n < 0 {
+			if _, ok := tier.windows[start]; !ok {
+				// the window is over,nothing to refund
+				continue
+			}
+		}
+		tier.windows[start] += n
+		tier.dirty = tier.dirty || tier.Persist
+	}
+}
+
+func (l *quotaLimiter) saveLoop() {
+	for {
+		timer := l.clock.NewTimer(l.saveInterval)
+		select {
+		case <-timer.C():
+			l.Flush()
+		case <-l.closing:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Flush saves the current window of the persisted tiers changed since the last save,
+// it returns the error of the store and the failed tiers are saved again next time
+func (l *quotaLimiter) Flush() error {
+	if l.store == nil {
+		return nil
+	}
+	l.saving.Lock()
+	defer l.saving.Unlock()
+	l.lock.Lock()
+	now := l.clock.Now()
+	var tiers []*quotaTier
+	var windows []QuotaWindow
+	for _, tier := range l.tiers {
+		if tier.dirty {
+			tier.dirty = false
+			tier.expire(now)
+			start := now.Truncate(tier.Period)
+			tiers = append(tiers, tier)
+			windows = append(windows, QuotaWindow{Start: start, Count: tier.windows[start.UnixNano()]})
+		}
+	}
+	l.lock.Unlock()
+	var err error
+	for i, tier := range tiers {
+		if saveErr := l.store.Save(l.storeKey(tier), windows[i]); saveErr != nil {
+			err = saveErr
+			l.lock.Lock()
+			tier.dirty = true
+			l.lock.Unlock()
+		}
+	}
+	if err != nil {
+		l.lock.Lock()
+		l.err = err
+		l.lock.Unlock()
+	}
+	return err
+}
+
+// Close stops the background saves and saves the pending changes,the limiter keeps limiting
+func (l *quotaLimiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closing)
+	})
+	return l.Flush()
+}
+
+// Err returns the last error of the store,nil without a store
+func (l *quotaLimiter) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.err
+}
+
+// TakeN takes n events when every tier admits them now,otherwise it takes nothing and tells which tier refused
+func (l *quotaLimiter) TakeN(n int64) QuotaDecision {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	var decision QuotaDecision
+	for _, tier := range l.tiers {
+		if n > tier.Limit {
+			return QuotaDecision{Tier: tier.Name}
+		}
+		tier.expire(now)
+		if wait := tier.fit(now, n).Sub(now); wait > decision.RetryAfter {
+			decision = QuotaDecision{Tier: tier.Name, RetryAfter: wait}
+		}
+	}
+	if decision.Tier != "" {
+		decision.RetryAfter = l.fit(now, n).Sub(now)
+		return decision
+	}
+	l.book(now, n)
+	return QuotaDecision{Allowed: true}
+}
+
+func (l *quotaLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+func (l *quotaLimiter) AllowN(n int64) bool {
+	return l.TakeN(n).Allowed
+}
+
+func (l *quotaLimiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN books n events on every tier in the first windows all of them admit the events in
+func (l *quotaLimiter) ReserveN(n int64) *Reservation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	for _, tier := range l.tiers {
+		if n > tier.Limit {
+			return &Reservation{}
+		}
+		tier.expire(now)
+	}
+	at := l.fit(now, n)
+	l.book(at, n)
+	return &Reservation{ok: true, timeToAct: at, clock: l.clock, refund: func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.book(at, -n)
+	}}
+}
+
+func (l *quotaLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until every tier admits n events
+func (l *quotaLimiter) WaitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitReservation(ctx, l.ReserveN(n))
+}
+
+// State returns the quota of the tier with the fewest events left,the one the caller runs into first
+func (l *quotaLimiter) State() RateLimitState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.clock.Now()
+	var state RateLimitState
+	for i, tier := range l.tiers {
+		tier.expire(now)
+		start := now.Truncate(tier.Period)
+		tierState := RateLimitState{
+			Limit:     tier.Limit,
+			Remaining: tier.Limit - tier.windows[start.UnixNano()],
+			Reset:     start.Add(tier.Period).Sub(now),
+		}
+		if tierState.Remaining <= 0 {
+			tierState.Remaining = 0
+			tierState.RetryAfter = tier.fit(now, 1).Sub(now)
+		}
+		if i == 0 || tierState.Remaining < state.Remaining ||
+			tierState.Remaining == state.Remaining && tierState.RetryAfter > state.RetryAfter {
+			state = tierState
+		}
+	}
+	return state
+}
@@ -0,0 +1,157 @@
+package golangUtil
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestQuotas(options ...quotaLimiterOptions) (*quotaLimiter, *fakeClock) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	options = append(options, WithQuotaClock(clock))
+	return NewQuotaLimiter([]Quota{
+		{Name: "second", Limit: 2, Period: time.Second},
+		{Name: "minute", Limit: 3, Period: time.Minute, Persist: true},
+	}, options...), clock
+}
+
+func TestQuotaLimiterTiers(t *testing.T) {
+	limiter, clock := newTestQuotas()
+	if !limiter.Allow() || !limiter.Allow() {
+		t.Fatal("burst of the second tier refused")
+	}
+	if decision := limiter.TakeN(1); decision.Allowed || decision.Tier != "second" || decision.RetryAfter != time.Second {
+		t.Fatalf("third event in a second gives %+v", decision)
+	}
+	clock.Advance(time.Second)
+	if !limiter.Allow() {
+		t.Fatal("third event of the minute refused")
+	}
+	clock.Advance(time.Second)
+	decision := limiter.TakeN(1)
+	if decision.Allowed || decision.Tier != "minute" || decision.RetryAfter != 58*time.Second {
+		t.Fatalf("fourth event in a minute gives %+v", decision)
+	}
+	// the refusal of the minute tier didn't charge the second tier
+	clock.Advance(58 * time.Second)
+	if decision = limiter.TakeN(1); !decision.Allowed {
+		t.Fatalf("event in the next minute gives %+v", decision)
+	}
+	if decision = limiter.TakeN(5); decision.Allowed || decision.Tier != "second" || decision.RetryAfter != 0 {
+		t.Fatalf("events over a limit give %+v", decision)
+	}
+}
+
+func TestQuotaLimiterPeriodCap(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	limiter := NewQuotaLimiter([]Quota{{Name: "second", Limit: 10, Period: time.Second}}, WithQuotaClock(clock))
+	admitted := map[int64]int{}
+	for i := 0; i < 3000; i++ {
+		if limiter.Allow() {
+			admitted[clock.Now().Unix()]++
+		}
+		clock.Advance(time.Millisecond)
+	}
+	for second := int64(0); second < 3; second++ {
+		if admitted[second] != 10 {
+			t.Fatalf("second %d admitted %d events,want 10", second, admitted[second])
+		}
+	}
+}
+
+func TestQuotaLimiterState(t *testing.T) {
+	limiter, clock := newTestQuotas()
+	if state := limiter.State(); state.Limit != 2 || state.Remaining != 2 {
+		t.Fatalf("fresh limiter state %+v", state)
+	}
+	limiter.Allow()
+	limiter.Allow()
+	clock.Advance(time.Second)
+	limiter.Allow()
+	state := limiter.State()
+	if state.Limit != 3 || state.Remaining != 0 || state.RetryAfter != 59*time.Second || state.Reset != 59*time.Second {
+		t.Fatalf("state with the minute tier used up %+v", state)
+	}
+}
+
+func TestQuotaLimiterReserve(t *testing.T) {
+	limiter, _ := newTestQuotas()
+	limiter.Allow()
+	limiter.Allow()
+	if r := limiter.Reserve(); !r.OK() || r.Delay() != time.Second {
+		t.Fatalf("reservation delay %v", r.Delay())
+	}
+	r := limiter.Reserve()
+	if !r.OK() || r.Delay() != time.Minute {
+		t.Fatalf("reservation past the minute tier delay %v", r.Delay())
+	}
+	r.Cancel()
+	if r = limiter.Reserve(); r.Delay() != time.Minute {
+		t.Fatalf("canceled reservation not refunded,delay %v", r.Delay())
+	}
+	if limiter.ReserveN(3).OK() {
+		t.Fatal("reservation over a limit granted")
+	}
+}
+
+type mapQuotaStore struct {
+	lock    sync.Mutex
+	windows map[string]QuotaWindow
+}
+
+func (m *mapQuotaStore) Load(key string) (QuotaWindow, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	window, ok := m.windows[key]
+	return window, ok, nil
+}
+
+func (m *mapQuotaStore) Save(key string, window QuotaWindow) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.windows[key] = window
+	return nil
+}
+
+func TestQuotaLimiterStore(t *testing.T) {
+	store := &mapQuotaStore{windows: map[string]QuotaWindow{}}
+	limiter, _ := newTestQuotas(WithQuotaStore(store, "caller"))
+	limiter.Allow()
+	limiter.Allow()
+	if _, ok, _ := store.Load("caller:minute"); ok {
+		t.Fatal("store saved on the path of a request")
+	}
+	if err := limiter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if window, ok, _ := store.Load("caller:minute"); !ok || window.Count != 2 || len(store.windows) != 1 {
+		t.Fatalf("store holds %v,want only the minute tier", store.windows)
+	}
+	// a restarted limiter keeps the minute tier and starts the second tier fresh
+	restarted, clock := newTestQuotas(WithQuotaStore(store, "caller"))
+	defer restarted.Close()
+	if !restarted.Allow() || restarted.Allow() {
+		t.Fatal("restarted limiter lost the minute tier")
+	}
+	clock.Advance(time.Minute)
+	if !restarted.Allow() || restarted.Err() != nil {
+		t.Fatal("minute tier didn't start over after the restart")
+	}
+}
+
+func TestQuotaLimiterSaveInterval(t *testing.T) {
+	store := &mapQuotaStore{windows: map[string]QuotaWindow{}}
+	limiter, clock := newTestQuotas(WithQuotaStore(store, "caller"), WithQuotaSaveInterval(time.Second))
+	defer limiter.Close()
+	limiter.Allow()
+	waitForTimers(clock, 1)
+	clock.Advance(time.Second)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if window, ok, _ := store.Load("caller:minute"); ok && window.Count == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background save never happened")
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package golangUtil
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,23 +17,114 @@ const WindowsSize int64 = 3 * TimeShift
 const MaxRequestPerWindows = WindowsSize / TimeShift * 1 << 12
 const RateLimitingError = "server is busy,please wait"
 
+// slideWindowsLimiter splits the window into sub windows numbered from timestamp on,
+// windows maps the number of a sub window to the permits taken in it,the ones of reservations included,
+// a sub window gets an entry once permits are taken in it so an idle limiter holds almost nothing.
+// the window admits permitsPerWindows over the last subWindowsSize sub windows
 type slideWindowsLimiter struct {
-	permitsPerWindows    int64
-	windows              map[int64]int64
+	permitsPerWindows int64
+	windows           map[int64]int64
+	// permits taken in the sub windows of the current window
 	totalCount           int64
 	lock                 sync.Mutex
-	once                 sync.Once
 	timestamp            int64
 	smallWindowsDistance int64
 	windowsSize          int64
-	clearFlag            int32
 	subWindowsSize       int64
-	cond                 *sync.Cond
-	options              rateLimiterOptions
+	// the current sub window,the last one counted in totalCount
+	head int64
+	// the last sub window holding a reservation,later than head while reservations wait
+	booked int64
+	// the last sub window permits were taken in,the window is empty once it leaves
+	lastTaken int64
+	clock     Clock
+	options   rateLimiterOptions
 }
 type rateLimiterOptions func(limiter *slideWindowsLimiter)
 type MiddleWare func(http.Handler) http.Handler
 
+// Limiter is the interface shared by the rate limiters,so that the algorithm can change without touching the code using it
+type Limiter interface {
+	// Allow reports whether one event may happen now
+	Allow() bool
+	// AllowN reports whether n events may happen now,either all of them are admitted or none
+	AllowN(n int64) bool
+	// Reserve takes a permit for one event ahead of time,the event may happen after the Delay of the reservation
+	Reserve() *Reservation
+	// Wait blocks until one event may happen,it returns early with an error when ctx is done first
+	Wait(ctx context.Context) error
+}
+
+var (
+	// ErrExceedsBurst is returned by Wait when more events are asked at once than the limiter ever admits together
+	ErrExceedsBurst = errors.New("rate limiter: events exceed the burst")
+	// ErrExceedsDeadline is returned by Wait when the permit comes after the deadline of the context
+	ErrExceedsDeadline = errors.New("rate limiter: wait would exceed the context deadline")
+)
+
+// Reservation is a permit taken from a Limiter,the event it stands for may only happen once Delay is over
+type Reservation struct {
+	ok        bool
+	timeToAct time.Time
+	clock     Clock
+	// gives the permit back to the limiter
+	refund   func()
+	canceled int32
+}
+
+// OK reports whether the limiter can grant the reservation at all,a reservation asking more than the burst never can
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before acting on the reservation,0 means now.
+// a reservation that isn't OK waits forever
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return math.MaxInt64
+	}
+	if delay := r.timeToAct.Sub(r.clock.Now()); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Cancel gives the permit back when the event won't happen,so that other callers may use it.
+// it does nothing once the time to act has passed
+func (r *Reservation) Cancel() {
+	if !r.ok || r.refund == nil || !r.clock.Now().Before(r.timeToAct) {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&r.canceled, 0, 1) {
+		r.refund()
+	}
+}
+
+// waitReservation sleeps until the time to act of r,a reservation that can't be honored in time is canceled
+func waitReservation(ctx context.Context, r *Reservation) error {
+	if !r.OK() {
+		return ErrExceedsBurst
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	// the deadline is on the real clock,compare what is left of it with the delay
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return ErrExceedsDeadline
+	}
+	timer := r.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
 func WithMaxPassingPerWindows(numbers int64) rateLimiterOptions {
 	return func(limiter *slideWindowsLimiter) {
 		limiter.permitsPerWindows = numbers
@@ -44,6 +139,14 @@ func WithSubWindowsNumber(numbers int64) rateLimiterOptions {
 	}
 }
 
+// WithClock sets the clock the limiter reads,tests use a fake clock to move the windows exactly
+func WithClock(clock Clock) rateLimiterOptions {
+	return func(limiter *slideWindowsLimiter) {
+		limiter.clock = clock
+		limiter.timestamp = clock.Now().UnixNano()
+	}
+}
+
 // WithWindowsSize function represent you can set windows size to promise the actual max Request numbers can‘t exceed the max request which you set in the period of time
 func WithWindowsSize(times time.Duration) rateLimiterOptions {
 	return func(limiter *slideWindowsLimiter) {
@@ -53,7 +156,6 @@ func WithWindowsSize(times time.Duration) rateLimiterOptions {
 }
 func init() {
 	slideLimiter = initDefaultLimiter()
-	deferCreateWindows(slideLimiter)
 }
 
 func getRateLimiterMiddleware(options ...rateLimiterOptions) (result *slideWindowsLimiter) {
@@ -61,43 +163,131 @@ func getRateLimiterMiddleware(options ...rateLimiterOptions) (result *slideWindo
 	for i := 0; i < len(options); i++ {
 		options[i](result)
 	}
-	deferCreateWindows(result)
+	if result.smallWindowsDistance < 1 {
+		result.smallWindowsDistance = 1
+	}
 	return result
 }
-func deferCreateWindows(limiter *slideWindowsLimiter) {
-	limiter.once.Do(func() {
-		var i int64 = 0
-		for ; i < limiter.subWindowsSize; i++ {
-			limiter.windows[i] = 0
-		}
-	})
+
+// NewSlideWindowsLimiter returns a slide windows limiter,the same one RegistryRateLimiting builds from options,
+// so that background jobs can Wait on the limiter the middleware uses
+func NewSlideWindowsLimiter(options ...rateLimiterOptions) *slideWindowsLimiter {
+	return getRateLimiterMiddleware(options...)
 }
 
 func initDefaultLimiter() (result *slideWindowsLimiter) {
 	result = &slideWindowsLimiter{
-		permitsPerWindows: MaxRequestPerWindows,
-		// windows length is  prime number may be can defeat conflict better
-		windows:              make(map[int64]int64, SmallWindows+3),
+		permitsPerWindows:    MaxRequestPerWindows,
+		windows:              make(map[int64]int64),
 		timestamp:            time.Now().UnixNano(),
 		lock:                 sync.Mutex{},
 		smallWindowsDistance: WindowsSize / SmallWindows,
 		windowsSize:          WindowsSize,
 		subWindowsSize:       SmallWindows,
+		clock:                SystemClock,
 	}
-	result.cond = sync.NewCond(&result.lock)
 	return result
 }
 
 func RegistryRateLimiting(options ...rateLimiterOptions) MiddleWare {
-	middleware := getRateLimiterMiddleware(options...)
+	return RegistryLimiter(getRateLimiterMiddleware(options...))
+}
+
+// RateLimitState is the quota of a limiter at one moment,the middleware writes it as the RateLimit headers
+type RateLimitState struct {
+	// permits the limiter admits at once
+	Limit int64
+	// permits left now
+	Remaining int64
+	// time until the quota is whole again
+	Reset time.Duration
+	// time until the next permit,0 while Remaining isn't 0
+	RetryAfter time.Duration
+}
+
+// stateLimiter is a Limiter able to tell its quota,the limiters of this package all are
+type stateLimiter interface {
+	State() RateLimitState
+}
+
+// RejectHandler writes the response of a request the limiter refused,
+// state is the zero value for a limiter that can't tell its quota
+type RejectHandler func(writer http.ResponseWriter, request *http.Request, state RateLimitState)
+
+// DefaultRejectHandler answers 429 Too Many Requests with Retry-After and RateLimitingError as the body
+func DefaultRejectHandler(writer http.ResponseWriter, request *http.Request, state RateLimitState) {
+	SetRetryAfter(writer.Header(), state)
+	writer.WriteHeader(http.StatusTooManyRequests)
+	writer.Write([]byte(RateLimitingError))
+}
+
+type rateLimitMiddleware struct {
+	reject RejectHandler
+}
+
+type rateLimitMiddlewareOptions func(middleware *rateLimitMiddleware)
+
+// WithRejectHandler replaces the response to refused requests,e.g. to write a JSON error envelope
+func WithRejectHandler(handler RejectHandler) rateLimitMiddlewareOptions {
+	return func(middleware *rateLimitMiddleware) {
+		middleware.reject = handler
+	}
+}
+
+func newRateLimitMiddleware(options []rateLimitMiddlewareOptions) *rateLimitMiddleware {
+	result := &rateLimitMiddleware{reject: DefaultRejectHandler}
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	return result
+}
+
+// serve lets the request through when limiter allows it and rejects it otherwise,
+// the RateLimit-Limit,RateLimit-Remaining and RateLimit-Reset headers are set on both
+func (m *rateLimitMiddleware) serve(limiter Limiter, next http.Handler, writer http.ResponseWriter, request *http.Request) {
+	allowed, state := AllowRequest(limiter, writer.Header())
+	if allowed {
+		next.ServeHTTP(writer, request)
+	} else {
+		m.reject(writer, request, state)
+	}
+}
+
+// AllowRequest takes a permit of limiter for a request and sets the RateLimit-Limit,RateLimit-Remaining and RateLimit-Reset
+// headers when limiter can tell its quota,state is the zero value otherwise.
+// it is the admission of RegistryLimiter,for the middlewares of other routers
+func AllowRequest(limiter Limiter, header http.Header) (allowed bool, state RateLimitState) {
+	allowed = limiter.Allow()
+	if stater, ok := limiter.(stateLimiter); ok {
+		state = stater.State()
+		header.Set("RateLimit-Limit", strconv.FormatInt(state.Limit, 10))
+		header.Set("RateLimit-Remaining", strconv.FormatInt(state.Remaining, 10))
+		header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(state.Reset, 0), 10))
+	}
+	return allowed, state
+}
+
+// SetRetryAfter sets the Retry-After header of a refused request,at least a second
+func SetRetryAfter(header http.Header, state RateLimitState) {
+	header.Set("Retry-After", strconv.FormatInt(ceilSeconds(state.RetryAfter, 1), 10))
+}
+
+// ceilSeconds rounds d up to whole seconds,no less than min
+func ceilSeconds(d time.Duration, min int64) int64 {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < min {
+		return min
+	}
+	return seconds
+}
+
+// RegistryLimiter returns a middleware admitting requests through limiter,whatever its algorithm.
+// refused requests get 429 from DefaultRejectHandler unless WithRejectHandler says otherwise
+func RegistryLimiter(limiter Limiter, options ...rateLimitMiddlewareOptions) MiddleWare {
+	middleware := newRateLimitMiddleware(options)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			if middleware.TryAcquire() {
-				next.ServeHTTP(writer, request)
-			} else {
-				writer.WriteHeader(500)
-				writer.Write([]byte(RateLimitingError))
-			}
+			middleware.serve(limiter, next, writer, request)
 		},
 		)
 	}
@@ -109,40 +299,212 @@ func TryAcquire() bool {
 	return slideLimiter.TryAcquire()
 }
 func (s *slideWindowsLimiter) TryAcquire() bool {
-	var diff int64
+	return s.AllowN(1)
+}
+
+func (s *slideWindowsLimiter) Allow() bool {
+	return s.AllowN(1)
+}
+
+// AllowN takes n permits in the current sub window,it fails while reservations are waiting so that they keep their turn
+func (s *slideWindowsLimiter) AllowN(n int64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	index := s.advance(s.clock.Now().UnixNano())
+	if s.booked > index || s.totalCount+n > s.permitsPerWindows {
+		return false
+	}
+	s.windows[index] += n
+	s.totalCount += n
+	s.lastTaken = index
+	return true
+}
+
+func (s *slideWindowsLimiter) Reserve() *Reservation {
+	return s.ReserveN(1)
+}
+
+// ReserveN takes n permits in the first sub window where they fit,after the reservations already waiting,
+// the reservation may be used once that sub window starts
+func (s *slideWindowsLimiter) ReserveN(n int64) *Reservation {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if n > s.permitsPerWindows {
+		return &Reservation{}
+	}
+	index := s.advance(s.clock.Now().UnixNano())
+	target := s.nextFit(index, n)
+	s.windows[target] += n
+	if target > s.lastTaken {
+		s.lastTaken = target
+	}
+	if target > index {
+		s.booked = target
+	} else {
+		s.totalCount += n
+	}
+	// the refund finds its sub window from its start time,Reconfigure may have changed the sub windows meanwhile
+	start := s.timestamp + target*s.smallWindowsDistance
+	return &Reservation{ok: true, timeToAct: time.Unix(0, start), clock: s.clock, refund: func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.refund(floorDiv(start-s.timestamp, s.smallWindowsDistance), n)
+	}}
+}
+
+func (s *slideWindowsLimiter) Wait(ctx context.Context) error {
+	return s.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n permits fit the window
+func (s *slideWindowsLimiter) WaitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitReservation(ctx, s.ReserveN(n))
+}
+
+// nextFit returns the first sub window from index on where n more permits fit,after the reservations already waiting,
+// n must not exceed the limit or no sub window ever fits
+func (s *slideWindowsLimiter) nextFit(index, n int64) int64 {
+	target := index
+	if s.booked > target {
+		target = s.booked
+	}
+	// permits over the window ending at target,then slide it one sub window at a time until n more fit
+	var count int64
+	for i, c := range s.windows {
+		if i > target-s.subWindowsSize && i <= target {
+			count += c
+		}
+	}
+	for count+n > s.permitsPerWindows {
+		count -= s.windows[target-s.subWindowsSize+1]
+		target++
+		count += s.windows[target]
+	}
+	return target
+}
+
+// State returns the quota of the window,Reset is when the last permit taken leaves it.
+// a limit of 0 never admits,it reports a window to wait
+func (s *slideWindowsLimiter) State() RateLimitState {
 	s.lock.Lock()
-	for atomic.LoadInt32(&s.clearFlag) != 0 {
-		s.cond.Wait()
-	}
-	diff = time.Now().UnixNano() - s.timestamp
-	var index = diff / s.smallWindowsDistance
-	if diff <= s.windowsSize {
-		if s.totalCount < s.permitsPerWindows {
-			s.totalCount++
-			s.windows[index]++
-			s.lock.Unlock()
-			return true
-		} else {
-			s.lock.Unlock()
-			return false
+	defer s.lock.Unlock()
+	now := s.clock.Now().UnixNano()
+	index := s.advance(now)
+	state := RateLimitState{Limit: s.permitsPerWindows}
+	if s.permitsPerWindows <= 0 {
+		state.Limit = 0
+		state.RetryAfter = time.Duration(s.windowsSize)
+	} else if s.booked <= index && s.totalCount < s.permitsPerWindows {
+		state.Remaining = s.permitsPerWindows - s.totalCount
+	} else {
+		state.RetryAfter = time.Duration(s.timestamp + s.nextFit(index, 1)*s.smallWindowsDistance - now)
+	}
+	if s.lastTaken > index-s.subWindowsSize {
+		state.Reset = time.Duration(s.timestamp + (s.lastTaken+s.subWindowsSize)*s.smallWindowsDistance - now)
+	}
+	return state
+}
+
+// advance moves the window to the sub window of now and returns its number:
+// sub windows leaving the window are dropped and the reservations of those entering it are counted
+func (s *slideWindowsLimiter) advance(now int64) int64 {
+	index := (now - s.timestamp) / s.smallWindowsDistance
+	if index <= s.head {
+		return s.head
+	}
+	if index-s.head >= s.subWindowsSize {
+		// the whole window went by,recount what is left
+		s.totalCount = 0
+		for i, c := range s.windows {
+			if i <= index-s.subWindowsSize {
+				delete(s.windows, i)
+			} else if i <= index {
+				s.totalCount += c
+			}
 		}
 	} else {
-		if atomic.CompareAndSwapInt32(&s.clearFlag, 0, 1) {
-			go func() {
-				s.lock.Lock()
-				defer s.lock.Unlock()
-				s.timestamp += diff
-				var i int64 = 0
-				var invalidWindows = index % s.subWindowsSize
-				for ; i <= invalidWindows; i++ {
-					s.totalCount -= s.windows[i]
-					s.windows[i] = 0
-				}
-				atomic.CompareAndSwapInt32(&s.clearFlag, 1, 0)
-				s.cond.Broadcast()
-			}()
+		for i := s.head + 1; i <= index; i++ {
+			expired := i - s.subWindowsSize
+			s.totalCount -= s.windows[expired]
+			delete(s.windows, expired)
+			s.totalCount += s.windows[i]
+		}
+	}
+	s.head = index
+	return index
+}
+
+// Reconfigure applies options to the running limiter without dropping the permits it counted,e.g. after a config reload:
+// the permits of the old sub windows are moved to the new sub windows covering the same time,the pending reservations too,
+// and a limit below the permits in the window only refuses new permits until the window drains
+func (s *slideWindowsLimiter) Reconfigure(options ...rateLimiterOptions) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	timestamp, distance := s.timestamp, s.smallWindowsDistance
+	for i := 0; i < len(options); i++ {
+		options[i](s)
+	}
+	if s.smallWindowsDistance < 1 {
+		s.smallWindowsDistance = 1
+	}
+	remap := func(index int64) int64 {
+		return floorDiv(timestamp+index*distance-s.timestamp, s.smallWindowsDistance)
+	}
+	windows := make(map[int64]int64, len(s.windows))
+	for i, c := range s.windows {
+		windows[remap(i)] += c
+	}
+	s.windows = windows
+	s.lastTaken = remap(s.lastTaken)
+	// recount the window as it stands in the new sub windows
+	s.head = floorDiv(s.clock.Now().UnixNano()-s.timestamp, s.smallWindowsDistance)
+	s.booked = s.head
+	s.totalCount = 0
+	for i, c := range s.windows {
+		switch {
+		case i <= s.head-s.subWindowsSize:
+			delete(s.windows, i)
+		case i <= s.head:
+			s.totalCount += c
+		case c > 0 && i > s.booked:
+			s.booked = i
+		}
+	}
+}
+
+// floorDiv divides rounding towards minus infinity,times before the timestamp fall in negative sub windows
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// ReconfigureRateLimiter reconfigures the limiter TryAcquire uses,see Reconfigure
+func ReconfigureRateLimiter(options ...rateLimiterOptions) {
+	slideLimiter.Reconfigure(options...)
+}
+
+// refund gives back the n permits a canceled reservation took in the sub window target
+func (s *slideWindowsLimiter) refund(target, n int64) {
+	s.advance(s.clock.Now().UnixNano())
+	if s.windows[target] < n {
+		return
+	}
+	s.windows[target] -= n
+	if target <= s.head {
+		s.totalCount -= n
+	}
+	if target == s.booked && s.windows[target] == 0 {
+		s.booked = s.head
+		for i, c := range s.windows {
+			if i > s.booked && c > 0 {
+				s.booked = i
+			}
 		}
-		s.lock.Unlock()
 	}
-	return s.TryAcquire()
 }
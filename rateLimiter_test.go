@@ -1,7 +1,12 @@
 package golangUtil
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -42,36 +47,321 @@ func BenchmarkTryAcquire(b *testing.B) {
 	group.Wait()
 }
 
+// many goroutines race for the permits of each window,exactly the limit is admitted every time
 func TestConcurrencyTryAcquire(t *testing.T) {
-	const times = 10000
-	group := sync.WaitGroup{}
-	group.Add(times)
-	var smoothTimes time.Duration = 30 * time.Millisecond
-	var resultInt int64
-	var cycle int64 = 0
-	for i := 0; i < times; i++ {
-		go func() {
-			defer group.Done()
-			time.Sleep(smoothTimes)
-			if cycle >= 100 && smoothTimes < 3*time.Second {
-				smoothTimes += smoothTimes * 10
-				cycle = 0
-			} else {
-				atomic.AddInt64(&cycle, 1)
-			}
-			result := TryAcquire()
-			if slideLimiter.totalCount > slideLimiter.permitsPerWindows {
-				panic(any("slide windows invalid"))
-			}
-			if result {
-				atomic.AddInt64(&resultInt, 1)
-			}
+	const limit, callers = 100, 1000
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewSlideWindowsLimiter(WithWindowsSize(time.Second), WithSubWindowsNumber(10), WithMaxPassingPerWindows(limit), WithClock(clock))
+	for window := 0; window < 5; window++ {
+		var admitted int64
+		group := sync.WaitGroup{}
+		group.Add(callers)
+		for i := 0; i < callers; i++ {
+			go func() {
+				defer group.Done()
+				if limiter.TryAcquire() {
+					atomic.AddInt64(&admitted, 1)
+				}
+			}()
+		}
+		group.Wait()
+		if admitted != limit {
+			t.Fatalf("window %d admitted %d of %d callers,want %d", window, admitted, callers, limit)
+		}
+		if state := limiter.State(); state.Remaining != 0 {
+			t.Fatalf("window %d has %d permits left", window, state.Remaining)
+		}
+		clock.Advance(time.Second)
+	}
+}
 
-		}()
+// newTestSlideWindows returns a window of 2 permits over 100ms in sub windows of 10ms on a fake clock
+func newTestSlideWindows() (*slideWindowsLimiter, *fakeClock) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewSlideWindowsLimiter(WithWindowsSize(100*time.Millisecond), WithSubWindowsNumber(10), WithMaxPassingPerWindows(2), WithClock(clock))
+	return limiter, clock
+}
+
+func TestSlideWindowsBurst(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	if !limiter.AllowN(2) || limiter.Allow() || limiter.AllowN(3) {
+		t.Fatal("burst of the window not enforced")
 	}
-	group.Wait()
-	println(resultInt)
-	for key, value := range slideLimiter.windows {
-		println(key, ":", value)
+	clock.Advance(99 * time.Millisecond)
+	if limiter.Allow() {
+		t.Fatal("permits left the window before it slid past them")
+	}
+	clock.Advance(time.Millisecond)
+	if !limiter.AllowN(2) {
+		t.Fatal("permits of the first sub window still counted after 100ms")
+	}
+}
+
+func TestSlideWindowsSubWindowExpiry(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.Allow()
+	clock.Advance(50 * time.Millisecond)
+	limiter.Allow()
+	if limiter.Allow() {
+		t.Fatal("third permit admitted")
+	}
+	// each permit leaves the window 100ms after its own sub window started
+	clock.Advance(50 * time.Millisecond)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("only the permit of the first sub window should have left")
+	}
+	clock.Advance(49 * time.Millisecond)
+	if limiter.Allow() {
+		t.Fatal("permit of the 50ms sub window left early")
+	}
+	clock.Advance(time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("permit of the 50ms sub window still counted")
+	}
+	if state := limiter.State(); state.Remaining != 0 || state.Reset != 100*time.Millisecond || state.RetryAfter != 50*time.Millisecond {
+		t.Fatalf("state = %+v", state)
+	}
+}
+
+func TestSlideWindowsRollover(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.AllowN(2)
+	// idle for many windows,nothing is left of the old ones
+	clock.Advance(time.Hour)
+	if !limiter.AllowN(2) || limiter.Allow() {
+		t.Fatal("window after a long idle period not fresh")
+	}
+	if len(limiter.windows) > 10 {
+		t.Fatalf("%d sub windows kept", len(limiter.windows))
+	}
+}
+
+func TestSlideWindowsReserve(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.AllowN(2)
+	var delays []time.Duration
+	var reservations []*Reservation
+	for i := 0; i < 3; i++ {
+		r := limiter.Reserve()
+		reservations = append(reservations, r)
+		delays = append(delays, r.Delay())
+	}
+	if delays[0] != 100*time.Millisecond || delays[1] != 100*time.Millisecond || delays[2] != 200*time.Millisecond {
+		t.Fatalf("delays = %v", delays)
+	}
+	// reservations keep their turn
+	if limiter.Allow() {
+		t.Fatal("Allow jumped the queue of reservations")
+	}
+	reservations[2].Cancel()
+	if delay := limiter.Reserve().Delay(); delay != 200*time.Millisecond {
+		t.Fatalf("reservation after a cancel waits %v", delay)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if reservations[0].Delay() != 0 || limiter.Allow() {
+		t.Fatal("reservations due now not counted in the window")
+	}
+	if r := limiter.ReserveN(3); r.OK() {
+		t.Fatal("reservation past the window is OK")
+	}
+}
+
+func TestSlideWindowsWait(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.AllowN(2)
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(context.Background())
+	}()
+	waitForTimers(clock, 1)
+	clock.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the window holds the permit of that wait,one is left
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != nil || clock.Timers() != 0 {
+		t.Fatalf("Wait with a permit free = %v", err)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := limiter.Wait(short); !errors.Is(err, ErrExceedsDeadline) {
+		t.Fatalf("Wait past the deadline = %v", err)
+	}
+
+	canceled, stop := context.WithCancel(context.Background())
+	go func() {
+		done <- limiter.Wait(canceled)
+	}()
+	waitForTimers(clock, 1)
+	stop()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait on a canceled context = %v", err)
+	}
+	// the canceled wait gave its permit back
+	if delay := limiter.Reserve().Delay(); delay != 100*time.Millisecond {
+		t.Fatalf("reservation after a canceled wait waits %v", delay)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	for _, limiter := range []Limiter{
+		NewTokenBucketLimiter(0.5, 2),
+		NewGCRALimiter(0.5, 2),
+		NewSlideWindowsLimiter(WithWindowsSize(4*time.Second), WithSubWindowsNumber(4), WithMaxPassingPerWindows(2)),
+	} {
+		handler := RegistryLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		var recorders []*httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			recorders = append(recorders, recorder)
+		}
+		first, rejected := recorders[0], recorders[2]
+		if first.Code != 200 || first.Header().Get("RateLimit-Limit") != "2" || first.Header().Get("RateLimit-Remaining") != "1" {
+			t.Errorf("%T: first response %d %v", limiter, first.Code, first.Header())
+		}
+		if first.Header().Get("Retry-After") != "" {
+			t.Errorf("%T: Retry-After on an admitted request", limiter)
+		}
+		header := rejected.Header()
+		if rejected.Code != http.StatusTooManyRequests || header.Get("RateLimit-Remaining") != "0" || rejected.Body.String() != RateLimitingError {
+			t.Errorf("%T: rejected response %d %v %q", limiter, rejected.Code, header, rejected.Body.String())
+		}
+		retry, _ := strconv.Atoi(header.Get("Retry-After"))
+		reset, _ := strconv.Atoi(header.Get("RateLimit-Reset"))
+		if retry < 1 || retry > 4 || reset < retry || reset > 4 {
+			t.Errorf("%T: Retry-After %d,RateLimit-Reset %d", limiter, retry, reset)
+		}
+	}
+}
+
+func TestRejectHandler(t *testing.T) {
+	limiter := NewTokenBucketLimiter(1, 1)
+	handler := RegistryLimiter(limiter, WithRejectHandler(func(w http.ResponseWriter, r *http.Request, state RateLimitState) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(w, `{"error":"throttled","limit":%d}`, state.Limit)
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	limiter.Allow()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != 429 || recorder.Body.String() != `{"error":"throttled","limit":1}` {
+		t.Fatalf("custom rejection = %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSlideWindowsReconfigure(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.Allow()
+	clock.Advance(50 * time.Millisecond)
+	limiter.Allow()
+	// a bigger limit keeps the 2 permits counted
+	limiter.Reconfigure(WithMaxPassingPerWindows(3))
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("raised limit didn't keep the permits counted")
+	}
+	// a limit below the count refuses until the window drains
+	limiter.Reconfigure(WithMaxPassingPerWindows(1))
+	clock.Advance(50 * time.Millisecond)
+	if limiter.Allow() {
+		t.Fatal("lowered limit admitted with 2 permits in the window")
+	}
+	clock.Advance(50 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("window not drained after the old permits left")
+	}
+
+	// sub windows of 20ms over a 200ms window,the permits keep their time
+	limiter, clock = newTestSlideWindows()
+	limiter.Allow()
+	clock.Advance(30 * time.Millisecond)
+	limiter.Allow()
+	limiter.Reconfigure(WithWindowsSize(200*time.Millisecond), WithSubWindowsNumber(10))
+	if state := limiter.State(); state.Remaining != 0 || state.RetryAfter != 170*time.Millisecond {
+		t.Fatalf("state after a bigger window = %+v", state)
+	}
+	clock.Advance(170 * time.Millisecond)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("first permit didn't leave the 200ms window on time")
+	}
+}
+
+func TestReconfigureRateLimiter(t *testing.T) {
+	defer ReconfigureRateLimiter(WithMaxPassingPerWindows(MaxRequestPerWindows))
+	ReconfigureRateLimiter(WithMaxPassingPerWindows(0))
+	if TryAcquire() {
+		t.Fatal("TryAcquire admitted with a limit of 0")
+	}
+}
+
+func TestSlideWindowsZeroLimit(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewSlideWindowsLimiter(WithWindowsSize(100*time.Millisecond), WithSubWindowsNumber(10), WithMaxPassingPerWindows(0), WithClock(clock))
+	handler := RegistryLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+		done <- recorder
+	}()
+	select {
+	case recorder := <-done:
+		if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("RateLimit-Limit") != "0" || recorder.Header().Get("Retry-After") != "1" {
+			t.Fatalf("response with a limit of 0: %d %v", recorder.Code, recorder.Header())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the middleware hangs with a limit of 0")
+	}
+	if state := limiter.State(); state.Remaining != 0 || state.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("state with a limit of 0 = %+v", state)
+	}
+	if limiter.Allow() || limiter.Reserve().OK() {
+		t.Fatal("a limit of 0 admitted a permit")
+	}
+}
+
+func TestSlideWindowsReconfigureToZero(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	observed := NewObservedLimiter("api", limiter, nil)
+	observed.Allow()
+	limiter.Reconfigure(WithMaxPassingPerWindows(0))
+	done := make(chan LimiterMetrics)
+	go func() {
+		observed.Allow()
+		done <- observed.Metrics()
+	}()
+	select {
+	case metrics := <-done:
+		if metrics.Allowed != 1 || metrics.Rejected != 1 || metrics.Utilization != 1 {
+			t.Fatalf("metrics with a limit of 0 = %+v", metrics)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the observed limiter hangs with a limit of 0")
+	}
+	limiter.Reconfigure(WithMaxPassingPerWindows(2))
+	clock.Advance(100 * time.Millisecond)
+	if !limiter.AllowN(2) {
+		t.Fatal("the limit didn't come back")
+	}
+}
+
+// a reservation taken before the sub windows change is refunded to the sub window now covering its time
+func TestSlideWindowsReconfigureRefund(t *testing.T) {
+	limiter, clock := newTestSlideWindows()
+	limiter.AllowN(2)
+	reservation := limiter.Reserve()
+	if reservation.Delay() != 100*time.Millisecond {
+		t.Fatalf("reservation delay = %v", reservation.Delay())
+	}
+	// sub windows of 20ms,the reservation moves from sub window 10 to 5
+	limiter.Reconfigure(WithSubWindowsNumber(5))
+	clock.Advance(10 * time.Millisecond)
+	reservation.Cancel()
+	clock.Advance(90 * time.Millisecond)
+	if !limiter.AllowN(2) || limiter.Allow() {
+		t.Fatal("the canceled reservation still holds its permit after the resize")
 	}
 }
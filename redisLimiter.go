@@ -0,0 +1,307 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisTimeout bounds a call of a redisLimiter to redis when WithRedisTimeout isn't given
+const DefaultRedisTimeout = 100 * time.Millisecond
+
+// DefaultRedisRetryInterval is how long a redisLimiter stays on its fallback after redis failed when WithRedisRetryInterval isn't given
+const DefaultRedisRetryInterval = time.Second
+
+// gcraScript runs the GCRA of gcraLimiter on a key holding the theoretical arrival time,in microseconds of the redis clock,
+// so that every replica shares one schedule and none depends on its own clock.
+// ARGV is the interval,the tolerance,n and the mode: 0 only reads,1 takes n permits when they fit now,2 reserves them.
+// it returns {allowed,how far the schedule runs ahead of now,wait before acting} with the times in microseconds.
+// numbers are written with %.0f,the default formatting of Lua would round the microseconds
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+if ARGV[4] == '0' then
+	return {1, tat - now, math.max(tat + interval - tolerance - now, 0)}
+end
+local new = tat + n * interval
+local wait = new - tolerance - now
+if wait > 0 and ARGV[4] == '1' then
+	return {0, tat - now, wait}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', new), 'PX', math.ceil((new - now) / 1000) + 1)
+return {1, new - now, math.max(wait, 0)}
+`)
+
+// gcraRefundScript moves the theoretical arrival time back by ARGV[1] microseconds for a canceled reservation
+var gcraRefundScript = redis.NewScript(`
+local tat = tonumber(redis.call('GET', KEYS[1]))
+local ttl = redis.call('PTTL', KEYS[1])
+if tat and ttl > 0 then
+	redis.call('SET', KEYS[1], string.format('%.0f', tat - tonumber(ARGV[1])), 'PX', ttl)
+end
+return 0
+`)
+
+// redisLimiter is a GCRA limiter whose schedule lives in redis,every replica using the same key shares one quota.
+// each decision is a single Lua script,so it is atomic without locks.
+// when redis fails the limiter falls back to a local limiter and retries redis after the retry interval,
+// the quota is then per replica until redis is back
+type redisLimiter struct {
+	client    redis.Scripter
+	key       string
+	interval  time.Duration
+	tolerance time.Duration
+	burst     int64
+
+	timeout       time.Duration
+	retryInterval time.Duration
+	fallback      Limiter
+	clock         Clock
+
+	lock sync.Mutex
+	// redis isn't called before then after a failure
+	downUntil time.Time
+	err       error
+	// the last reply of redis and when it came,State answers from it without a round trip
+	last   gcraReply
+	lastAt time.Time
+}
+
+// errScriptReply is kept as the error of redis when the script answers something else than its 3 numbers
+var errScriptReply = errors.New("redis limiter: unexpected script reply")
+
+type gcraReply struct {
+	allowed bool
+	ahead   time.Duration
+	wait    time.Duration
+}
+
+type redisLimiterOptions func(limiter *redisLimiter)
+
+// WithRedisTimeout bounds every call to redis,past it the call counts as a failure
+func WithRedisTimeout(timeout time.Duration) redisLimiterOptions {
+	return func(limiter *redisLimiter) {
+		limiter.timeout = timeout
+	}
+}
+
+// WithRedisRetryInterval sets how long the fallback is used after redis failed before redis is tried again
+func WithRedisRetryInterval(interval time.Duration) redisLimiterOptions {
+	return func(limiter *redisLimiter) {
+		limiter.retryInterval = interval
+	}
+}
+
+// WithRedisClock sets the clock of the limiter,the schedule in redis follows the clock of redis
+// but the delays of reservations and the retry interval are measured with this one
+func WithRedisClock(clock Clock) redisLimiterOptions {
+	return func(limiter *redisLimiter) {
+		limiter.clock = clock
+	}
+}
+
+// WithRedisFallback sets the limiter used while redis is unreachable,a local GCRA limiter with the same rate and burst by default
+func WithRedisFallback(fallback Limiter) redisLimiterOptions {
+	return func(limiter *redisLimiter) {
+		limiter.fallback = fallback
+	}
+}
+
+// NewRedisLimiter returns a limiter admitting ratePerSecond events per second and up to burst ahead of schedule
+// over every replica sharing key,client is a *redis.Client,a *redis.ClusterClient or any redis.Scripter
+func NewRedisLimiter(client redis.Scripter, key string, ratePerSecond float64, burst int64, options ...redisLimiterOptions) *redisLimiter {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("redis limiter needs a positive rate and burst"))
+	}
+	result := &redisLimiter{
+		client:        client,
+		key:           key,
+		timeout:       DefaultRedisTimeout,
+		retryInterval: DefaultRedisRetryInterval,
+		clock:         SystemClock,
+	}
+	result.setLimit(ratePerSecond, burst)
+	for i := 0; i < len(options); i++ {
+		options[i](result)
+	}
+	if result.fallback == nil {
+		result.fallback = NewGCRALimiter(ratePerSecond, burst, WithBucketClock(result.clock))
+	}
+	return result
+}
+
+// SetLimit changes the rate and the burst of this replica,the schedule in redis is kept.
+// replicas sharing the key should all be given the same limit,the fallback is changed too when it has SetLimit
+func (l *redisLimiter) SetLimit(ratePerSecond float64, burst int64) {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("redis limiter needs a positive rate and burst"))
+	}
+	l.lock.Lock()
+	l.setLimit(ratePerSecond, burst)
+	l.lock.Unlock()
+	if fallback, ok := l.fallback.(interface{ SetLimit(float64, int64) }); ok {
+		fallback.SetLimit(ratePerSecond, burst)
+	}
+}
+
+func (l *redisLimiter) setLimit(ratePerSecond float64, burst int64) {
+	l.interval = time.Duration(float64(time.Second) / ratePerSecond)
+	l.tolerance = time.Duration(burst) * l.interval
+	l.burst = burst
+}
+
+// limits returns the interval,the tolerance and the burst
+func (l *redisLimiter) limits() (time.Duration, time.Duration, int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.interval, l.tolerance, l.burst
+}
+
+// run calls the script in mode,false means redis is down and the fallback decides
+func (l *redisLimiter) run(mode, n int64) (gcraReply, bool) {
+	l.lock.Lock()
+	down := l.clock.Now().Before(l.downUntil)
+	interval, tolerance := l.interval, l.tolerance
+	l.lock.Unlock()
+	if down {
+		return gcraReply{}, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	values, err := gcraScript.Run(ctx, l.client, []string{l.key},
+		interval.Microseconds(), tolerance.Microseconds(), n, mode).Int64Slice()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err == nil && len(values) != 3 {
+		err = errScriptReply
+	}
+	if err != nil {
+		l.err = err
+		l.downUntil = l.clock.Now().Add(l.retryInterval)
+		return gcraReply{}, false
+	}
+	l.err = nil
+	reply := gcraReply{
+		allowed: values[0] == 1,
+		ahead:   time.Duration(values[1]) * time.Microsecond,
+		wait:    time.Duration(values[2]) * time.Microsecond,
+	}
+	l.last, l.lastAt = reply, l.clock.Now()
+	return reply, true
+}
+
+// Err returns the error of the last call to redis,nil while redis answers
+func (l *redisLimiter) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.err
+}
+
+func (l *redisLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+func (l *redisLimiter) AllowN(n int64) bool {
+	if _, _, burst := l.limits(); n > burst {
+		return false
+	}
+	if reply, ok := l.run(1, n); ok {
+		return reply.allowed
+	}
+	return l.fallback.AllowN(n)
+}
+
+func (l *redisLimiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN books n events on the shared schedule,the reservation comes from the fallback while redis is down
+func (l *redisLimiter) ReserveN(n int64) *Reservation {
+	interval, _, burst := l.limits()
+	if n > burst {
+		return &Reservation{}
+	}
+	reply, ok := l.run(2, n)
+	if !ok {
+		if fallback, ok := l.fallback.(interface{ ReserveN(int64) *Reservation }); ok {
+			return fallback.ReserveN(n)
+		}
+		if n == 1 {
+			return l.fallback.Reserve()
+		}
+		return &Reservation{}
+	}
+	return &Reservation{ok: true, timeToAct: l.clock.Now().Add(reply.wait), clock: l.clock, refund: func() {
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		defer cancel()
+		// a lost refund only costs the permit
+		gcraRefundScript.Run(ctx, l.client, []string{l.key}, (time.Duration(n) * interval).Microseconds())
+	}}
+}
+
+func (l *redisLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events fit the shared schedule
+func (l *redisLimiter) WaitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitReservation(ctx, l.ReserveN(n))
+}
+
+// State returns the quota of the shared schedule as of the last reply of redis,
+// it only asks redis when there is no reply yet
+func (l *redisLimiter) State() RateLimitState {
+	l.lock.Lock()
+	reply, at := l.last, l.lastAt
+	down := l.clock.Now().Before(l.downUntil)
+	l.lock.Unlock()
+	if down {
+		return l.fallbackState()
+	}
+	if at.IsZero() {
+		var ok bool
+		if reply, ok = l.run(0, 0); !ok {
+			return l.fallbackState()
+		}
+		at = l.clock.Now()
+	}
+	interval, tolerance, burst := l.limits()
+	ahead := reply.ahead - l.clock.Now().Sub(at)
+	if ahead < 0 {
+		ahead = 0
+	}
+	state := RateLimitState{
+		Limit:     burst,
+		Remaining: int64((tolerance - ahead) / interval),
+		Reset:     ahead,
+	}
+	if state.Remaining < 0 {
+		state.Remaining = 0
+	}
+	if state.Remaining == 0 {
+		state.RetryAfter = ahead + interval - tolerance
+	}
+	return state
+}
+
+func (l *redisLimiter) fallbackState() RateLimitState {
+	if stater, ok := l.fallback.(stateLimiter); ok {
+		return stater.State()
+	}
+	_, _, burst := l.limits()
+	return RateLimitState{Limit: burst}
+}
@@ -0,0 +1,92 @@
+package golangUtil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTestTime is the time the redis of the tests is stopped at
+var redisTestTime = time.Unix(1700000000, 0)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	server.SetTime(redisTestTime)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestRedisLimiterSharedQuota(t *testing.T) {
+	server, client := newTestRedis(t)
+	// two replicas on one key
+	a := NewRedisLimiter(client, "rate:api", 1, 3)
+	b := NewRedisLimiter(client, "rate:api", 1, 3)
+	if !a.AllowN(2) || !b.Allow() {
+		t.Fatal("burst of the shared key rejected")
+	}
+	if a.Allow() || b.Allow() {
+		t.Fatal("replicas admitted more than the shared burst")
+	}
+	if other := NewRedisLimiter(client, "rate:other", 1, 3); !other.Allow() {
+		t.Fatal("another key shares the quota")
+	}
+	server.SetTime(redisTestTime.Add(time.Second))
+	if !b.Allow() || a.Allow() {
+		t.Fatal("one second at 1/s didn't give back exactly one permit")
+	}
+	if state := a.State(); state.Limit != 3 || state.Remaining != 0 || state.RetryAfter <= 900*time.Millisecond {
+		t.Fatalf("state = %+v", state)
+	}
+	if a.Err() != nil {
+		t.Fatal(a.Err())
+	}
+}
+
+func TestRedisLimiterReserve(t *testing.T) {
+	_, client := newTestRedis(t)
+	clock := NewFakeClock(redisTestTime)
+	limiter := NewRedisLimiter(client, "rate:jobs", 1, 1, WithRedisClock(clock))
+	if r := limiter.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("first reservation waits %v", r.Delay())
+	}
+	r := limiter.Reserve()
+	if delay := r.Delay(); delay != time.Second {
+		t.Fatalf("second reservation waits %v,want 1s", delay)
+	}
+	r.Cancel()
+	if delay := limiter.Reserve().Delay(); delay != time.Second {
+		t.Fatalf("reservation after a cancel waits %v", delay)
+	}
+	if limiter.ReserveN(2).OK() {
+		t.Fatal("reservation past the burst is OK")
+	}
+}
+
+func TestRedisLimiterFallback(t *testing.T) {
+	server, client := newTestRedis(t)
+	clock := NewFakeClock(redisTestTime)
+	limiter := NewRedisLimiter(client, "rate:api", 1, 2, WithRedisRetryInterval(time.Second), WithRedisTimeout(50*time.Millisecond), WithRedisClock(clock))
+	limiter.AllowN(2)
+	server.Close()
+	// the local fallback has a quota of its own
+	if !limiter.AllowN(2) || limiter.Allow() {
+		t.Fatal("fallback doesn't limit like the local GCRA")
+	}
+	if limiter.Err() == nil {
+		t.Fatal("Err is nil with redis down")
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.Allow() || limiter.Err() == nil {
+		t.Fatal("redis tried again before the retry interval")
+	}
+	clock.Advance(time.Second)
+	// the key is back and still holds the schedule of before
+	if limiter.Allow() || limiter.Err() != nil {
+		t.Fatalf("redis not used again after the retry interval,err %v", limiter.Err())
+	}
+}
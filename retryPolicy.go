@@ -0,0 +1,134 @@
+package golangUtil
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before the next attempt of a task,
+// attempt is the number of attempts made so far,from 1,and previous the delay before the last one
+type Backoff func(attempt int, previous time.Duration) time.Duration
+
+// ConstantBackoff waits delay before every attempt
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(attempt int, previous time.Duration) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff doubles the delay after every attempt from base,up to max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int, previous time.Duration) time.Duration {
+		return exponentialDelay(base, max, attempt)
+	}
+}
+
+// FullJitterBackoff waits a random delay up to the one of ExponentialBackoff,
+// so tasks failing together don't come back together
+func FullJitterBackoff(base, max time.Duration) Backoff {
+	return func(attempt int, previous time.Duration) time.Duration {
+		return randomDelay(0, exponentialDelay(base, max, attempt))
+	}
+}
+
+// DecorrelatedJitterBackoff waits a random delay between base and three times the previous one,up to max.
+// the delays grow like ExponentialBackoff on average but spread more than with full jitter
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return func(attempt int, previous time.Duration) time.Duration {
+		if previous < base {
+			previous = base
+		}
+		upper := previous * 3
+		if upper > max || upper < previous {
+			upper = max
+		}
+		return randomDelay(base, upper)
+	}
+}
+
+func exponentialDelay(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	// past 62 doublings the delay overflows,and past max it doesn't matter
+	if attempt > 62 || base<<(attempt-1)>>(attempt-1) != base {
+		return max
+	}
+	delay := base << (attempt - 1)
+	if delay > max || delay <= 0 {
+		return max
+	}
+	return delay
+}
+
+// randomDelay returns a delay in [min,max]
+func randomDelay(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
+
+// permanentError marks an error retrying can't fix
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent wraps err so that the retry queue gives the task up at once instead of retrying it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent tells whether err was wrapped by Permanent,it is the classifier of a RetryPolicy without one
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryPolicy decides whether a failed task runs again and after how long,the zero value retries forever
+// with the delay of the queue
+type RetryPolicy struct {
+	// MaxAttempts gives the task up after that many attempts,0 for no limit
+	MaxAttempts int
+	// Backoff is the delay before each retry,the retry delay of the queue when nil
+	Backoff Backoff
+	// MaxElapsed gives the task up when the next attempt would start that long after the first one,0 for no limit
+	MaxElapsed time.Duration
+	// Permanent tells the errors not worth retrying,IsPermanent when nil
+	Permanent func(err error) bool
+}
+
+// next returns the delay before the next attempt of task after it failed with err at now,
+// false when the task should be given up
+func (p *RetryPolicy) next(task *task, err error, now time.Time, defaultDelay time.Duration) (time.Duration, bool) {
+	permanent := p.Permanent
+	if permanent == nil {
+		permanent = IsPermanent
+	}
+	if permanent(err) {
+		return 0, false
+	}
+	if p.MaxAttempts > 0 && task.attempts >= p.MaxAttempts {
+		return 0, false
+	}
+	delay := defaultDelay
+	if p.Backoff != nil {
+		delay = p.Backoff(task.attempts, task.lastDelay)
+	}
+	if p.MaxElapsed > 0 && now.Add(delay).Sub(task.firstRun) > p.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
@@ -0,0 +1,69 @@
+package golangUtil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, time.Second)
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 4: 80 * time.Millisecond, 8: time.Second, 100: time.Second} {
+		if delay := backoff(attempt, 0); delay != want {
+			t.Fatalf("attempt %d waits %v,want %v", attempt, delay, want)
+		}
+	}
+}
+
+func TestJitterBackoff(t *testing.T) {
+	full := FullJitterBackoff(10*time.Millisecond, time.Second)
+	decorrelated := DecorrelatedJitterBackoff(10*time.Millisecond, time.Second)
+	previous := time.Duration(0)
+	for i := 0; i < 1000; i++ {
+		attempt := i%12 + 1
+		if delay := full(attempt, 0); delay < 0 || delay > ExponentialBackoff(10*time.Millisecond, time.Second)(attempt, 0) {
+			t.Fatalf("full jitter attempt %d waits %v", attempt, delay)
+		}
+		delay := decorrelated(attempt, previous)
+		upper := previous * 3
+		if upper < 30*time.Millisecond {
+			upper = 30 * time.Millisecond
+		}
+		if upper > time.Second {
+			upper = time.Second
+		}
+		if delay < 10*time.Millisecond || delay > upper {
+			t.Fatalf("decorrelated jitter after %v waits %v", previous, delay)
+		}
+		previous = delay
+	}
+}
+
+func TestRetryPolicyNext(t *testing.T) {
+	start := time.Unix(0, 0)
+	failed := &task{attempts: 1, firstRun: start}
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: ExponentialBackoff(time.Second, time.Minute), MaxElapsed: 10 * time.Second}
+	if delay, retry := policy.next(failed, errors.New("down"), start, 0); !retry || delay != time.Second {
+		t.Fatalf("first failure gives %v,%v", delay, retry)
+	}
+	if _, retry := policy.next(failed, fmt.Errorf("bad request: %w", Permanent(errors.New("invalid"))), start, 0); retry {
+		t.Fatal("permanent error retried")
+	}
+	failed.attempts = 3
+	if _, retry := policy.next(failed, errors.New("down"), start, 0); retry {
+		t.Fatal("task retried past MaxAttempts")
+	}
+	failed.attempts = 2
+	if _, retry := policy.next(failed, errors.New("down"), start.Add(9*time.Second), 0); retry {
+		t.Fatal("task retried past MaxElapsed")
+	}
+	var zero RetryPolicy
+	if delay, retry := zero.next(failed, errors.New("down"), start, 5*time.Millisecond); !retry || delay != 5*time.Millisecond {
+		t.Fatalf("zero policy gives %v,%v,want the delay of the queue", delay, retry)
+	}
+	custom := RetryPolicy{Permanent: func(err error) bool { return err.Error() == "404" }}
+	if _, retry := custom.next(failed, errors.New("404"), start, 0); retry {
+		t.Fatal("error classified permanent retried")
+	}
+}
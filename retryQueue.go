@@ -1,12 +1,13 @@
 package golangUtil
 
-
 import (
-"container/heap"
-"sync"
-"sync/atomic"
-"time"
-"unsafe"
+	containerHeap "container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 )
 
 const HighPriority uint8 = 255
@@ -22,7 +23,7 @@ const defaultDelayTimePerTimes = 200 * time.Millisecond
 //auto begin the retryQueue
 //func init() {
 //	queue = NewRetryQuery(defaultDelayTimePerTimes)
-//	go queue.Run()
+//	go queue.Run(context.Background())
 //}
 //
 //func AddTask(function func() error, priority uint8) {
@@ -33,6 +34,22 @@ const defaultConcurrencyNumber = 5
 const defaultMaxRetryTimesPerTime = 3
 const defaultMaxSleepTime = 3 * time.Second
 
+var (
+	// ErrQueueClosed is returned when a task is added after Shutdown,or Run is called after it
+	ErrQueueClosed = errors.New("retry queue: closed")
+	// ErrQueueRunning is returned by Run when the queue already runs
+	ErrQueueRunning = errors.New("retry queue: already running")
+	// ErrScheduleEnded is returned for a recurring task whose schedule has no run left
+	ErrScheduleEnded = errors.New("retry queue: schedule has no next run")
+)
+
+// states of a retryQueue
+const (
+	queueIdle int32 = iota
+	queueRunning
+	queueClosed
+)
+
 type retryQueue struct {
 	//arena atomic operation
 	rw                   sync.RWMutex
@@ -43,9 +60,36 @@ type retryQueue struct {
 	maxRetryTimesPerTime int32
 	workerNumber         int32
 	maxSleepTime         time.Duration
-	//let query sleep
-	remained chan struct{}
-	loaf     bool
+	// tasks not due yet by run time,they move to arena when due
+	waiting scheduleArena
+	// tells Run the queue changed,so it looks again for the next due task
+	wake chan struct{}
+
+	state int32
+	// closed by Shutdown,no task is taken after it
+	closing   chan struct{}
+	closeOnce sync.Once
+	// closed when Run returns,or by Shutdown when Run never ran
+	stopped chan struct{}
+	// the context of the tasks,canceled to abort them
+	taskCtx     context.Context
+	cancelTasks context.CancelFunc
+	// workers running a task
+	running sync.WaitGroup
+	// policy of the tasks added without WithRetryPolicy
+	defaultPolicy RetryPolicy
+	hooks         TaskHooks
+	deadLetters   DeadLetterSink
+	// id of the last task added
+	lastID uint64
+	// the handlers and the log of a durable queue
+	handlers       map[string]durableHandler
+	wal            *retryWAL
+	walSegmentSize int64
+	walMaxSegments int
+	walSync        bool
+	// the clock of the run times,the backoffs and the attempts
+	clock Clock
 }
 type Option func(retryQuery *retryQueue)
 
@@ -54,6 +98,10 @@ func WithConcurrencyNumber(number int32) Option {
 		retryQuery.workerNumber = number
 	}
 }
+
+// WithMaxSleepTime bounded the sleep of Run between two looks at an empty queue.
+//
+// Deprecated: Run is woken when a task is added or due instead of polling,the option has no effect
 func WithMaxSleepTime(time time.Duration) Option {
 	return func(retryQuery *retryQueue) {
 		retryQuery.maxSleepTime = time
@@ -65,16 +113,48 @@ func WithMaxRetryTimesPerTime(number int32) Option {
 	}
 }
 
+// WithDefaultRetryPolicy sets the policy of the tasks added without WithRetryPolicy,
+// by default they are retried forever with the retry delay of the queue
+func WithDefaultRetryPolicy(policy RetryPolicy) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.defaultPolicy = policy
+	}
+}
+
+// WithQueueClock sets the clock the queue plans and waits with,tests use a fake clock to move through the backoffs
+func WithQueueClock(clock Clock) Option {
+	return func(retryQuery *retryQueue) {
+		retryQuery.clock = clock
+	}
+}
+
+type taskOptions func(task *task)
+
+// WithRetryPolicy sets how the task is retried,a durable task gets it from WithHandler
+func WithRetryPolicy(policy RetryPolicy) taskOptions {
+	return func(task *task) {
+		task.policy = policy
+		task.notLoggable = true
+	}
+}
+
 func NewRetryQuery(retryDelay time.Duration, options ...Option) *retryQueue {
 	res := &retryQueue{
 		workerNumber:         defaultConcurrencyNumber,
 		maxRetryTimesPerTime: defaultMaxRetryTimesPerTime,
 		arena:                make(taskArena, 0),
 		rw:                   sync.RWMutex{},
-		remained:             make(chan struct{}, 1),
 		maxSleepTime:         defaultMaxSleepTime,
-		loaf:                 true,
+		wake:                 make(chan struct{}, 1),
+		handlers:             make(map[string]durableHandler),
+		walSegmentSize:       DefaultWALSegmentSize,
+		walMaxSegments:       DefaultWALMaxSegments,
+		walSync:              true,
+		closing:              make(chan struct{}),
+		stopped:              make(chan struct{}),
+		clock:                SystemClock,
 	}
+	res.taskCtx, res.cancelTasks = context.WithCancel(context.Background())
 	res.check()
 	for i := 0; i < len(options); i++ {
 		options[i](res)
@@ -103,39 +183,181 @@ func (worker *worker) addTask(task *task) {
 	worker.task = task
 }
 
+// work runs the task up to retryTimes times,waiting the backoff of its policy between the attempts.
+// a task still failing goes back to the queue once its backoff is over,a task its policy gives up goes to the dead letter sink.
+// once the tasks are aborted it stops retrying and puts the task back at once,an attempt failing because of the abort doesn't count
 func (work *worker) work() {
-	// request work
-	var curRetryTimes = 0
+	defer func() {
+		work.query.running.Done()
+		work.query.signal <- int(work.index)
+	}()
 	if work.task == nil {
 		panic("concurrent RetryQuery panic")
 	}
-	for {
-		err := work.task.exec()
-		if err == nil {
-			break
-		} else {
-			if curRetryTimes+1 >= int(work.retryTimes) {
-				work.query.rw.Lock()
-				heap.Push(&work.query.arena, work.task)
-				work.query.rw.Unlock()
-				break
+	ctx, clock := work.query.taskCtx, work.query.clock
+	for curRetryTimes := 0; ; curRetryTimes++ {
+		start := clock.Now()
+		if work.task.attempts == 0 {
+			work.task.firstRun = start
+		}
+		work.task.attempts++
+		err := work.task.exec(ctx)
+		if err != nil && ctx.Err() != nil {
+			work.task.attempts--
+			if work.task.attempts == 0 {
+				work.task.firstRun = time.Time{}
 			}
+			work.query.push(work.task)
+			return
+		}
+		work.task.record(Attempt{Start: start, Duration: clock.Now().Sub(start), Err: err})
+		if err == nil {
+			work.query.complete(work.task)
+			work.query.reschedule(work.task)
+			return
+		}
+		work.query.fail(work.task)
+		delay, retry := work.task.policy.next(work.task, err, clock.Now(), work.retryDelay)
+		if !retry {
+			work.query.giveUp(work.task)
+			work.query.reschedule(work.task)
+			return
+		}
+		work.task.lastDelay = delay
+		work.query.logAttempt(work.task, clock.Now().Add(delay))
+		if ctx.Err() != nil {
+			work.query.push(work.task)
+			return
+		}
+		if curRetryTimes+1 >= int(work.retryTimes) {
+			work.query.pushAfter(work.task, delay)
+			return
+		}
+		timer := clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			work.query.push(work.task)
+			return
 		}
-		time.Sleep(work.retryDelay)
-		curRetryTimes++
 	}
-	work.query.signal <- int(work.index)
 }
 
+// taskArena holds the due tasks by priority
 type taskArena []*task
 
+// scheduleArena holds the tasks not due yet by run time,then by priority
+type scheduleArena []*task
+
 type task struct {
-	exec     func() error
+	id       uint64
+	name     string
+	exec     func(ctx context.Context) error
 	priority uint8
+	policy   RetryPolicy
+	hooks    TaskHooks
+	// the options the task was added with,a dead letter is redriven with them
+	options []taskOptions
+	// set by the options the log of a durable queue can't keep
+	notLoggable bool
+	// attempts made so far,when the first one started and the backoff before the last retry
+	attempts  int
+	firstRun  time.Time
+	lastDelay time.Duration
+	history   []Attempt
+	// the task isn't due before runAt,a recurring task runs again at the next time of its schedule
+	runAt    time.Time
+	schedule Schedule
+	// a durable task runs the registered handler with payload
+	handler string
+	payload []byte
+}
+
+func (task *task) record(attempt Attempt) {
+	if len(task.history) >= maxAttemptHistory {
+		task.history = append(task.history[:0], task.history[1:]...)
+	}
+	task.history = append(task.history, attempt)
+}
+
+func (task *task) outcome() TaskOutcome {
+	last := task.history[len(task.history)-1]
+	return TaskOutcome{
+		ID:       task.id,
+		Name:     task.name,
+		Priority: task.priority,
+		Attempts: task.attempts,
+		History:  append([]Attempt(nil), task.history...),
+		Err:      last.Err,
+	}
+}
+
+func (query *retryQueue) complete(task *task) {
+	query.logDone(task)
+	callHook(task, query.hooks.OnComplete, task.hooks.OnComplete)
+}
+
+func (query *retryQueue) fail(task *task) {
+	callHook(task, query.hooks.OnFailure, task.hooks.OnFailure)
+}
+
+// giveUp hands task to the dead letter sink,a durable task stays in the log until its letter is released
+func (query *retryQueue) giveUp(task *task) {
+	if query.deadLetters == nil {
+		query.logDone(task)
+		callHook(task, query.hooks.OnGiveUp, task.hooks.OnGiveUp)
+		return
+	}
+	letter := task.deadLetter(query.clock.Now())
+	if query.wal != nil && task.handler != "" {
+		query.wal.fail(query.wal.dead(task.id, uint64(task.attempts), letter.FailedAt, task.history))
+		letter.wal = query.wal
+	}
+	callHook(task, query.hooks.OnGiveUp, task.hooks.OnGiveUp)
+	query.deadLetters.DeadLetter(letter)
+}
+
+func (task *task) deadLetter(failedAt time.Time) DeadLetter {
+	return DeadLetter{
+		TaskOutcome: task.outcome(),
+		Exec:        task.exec,
+		Policy:      task.policy,
+		Handler:     task.handler,
+		Payload:     task.payload,
+		FailedAt:    failedAt,
+		options:     task.options,
+	}
+}
+
+// callHook calls the hook of the queue and then the one of the task,when they are set
+func callHook(task *task, hooks ...func(outcome TaskOutcome)) {
+	for _, hook := range hooks {
+		if hook != nil {
+			hook(task.outcome())
+		}
+	}
+}
+
+// PendingTask is a task still queued when the queue shut down,it can be added again to another queue
+type PendingTask struct {
+	ID       uint64
+	Name     string
+	Exec     func(ctx context.Context) error
+	Priority uint8
+	Policy   RetryPolicy
+	// Attempts is the number of times the task already ran
+	Attempts int
+	// RunAt is when the task was due,Schedule is set for a recurring task
+	RunAt    time.Time
+	Schedule Schedule
+	// Handler and Payload are set for a durable task,which also stays in the log to be replayed
+	Handler string
+	Payload []byte
 }
 
 func (query *retryQueue) check() {
-	if !atomic.CompareAndSwapUintptr((*uintptr)(&query.nocopy), uintptr(0), uintptr(unsafe.Pointer(query))) && unsafe.Pointer(query.nocopy) != unsafe.Pointer(query) {
+	if !atomic.CompareAndSwapUintptr((*uintptr)(&query.nocopy), uintptr(0), uintptr(unsafe.Pointer(query))) && uintptr(query.nocopy) != uintptr(unsafe.Pointer(query)) {
 		panic("task copy")
 	}
 }
@@ -173,59 +395,285 @@ func (arena *taskArena) Pop() interface{} {
 	*arena = old[0 : n-1]
 	return x
 }
-func (query *retryQueue) AddTask(function func() error, priority uint8) {
+
+func (arena scheduleArena) Less(i, j int) bool {
+	if !arena[i].runAt.Equal(arena[j].runAt) {
+		return arena[i].runAt.Before(arena[j].runAt)
+	}
+	return arena[i].priority > arena[j].priority
+}
+func (arena scheduleArena) Len() int {
+	return len(arena)
+}
+func (arena scheduleArena) Swap(i, j int) {
+	arena[i], arena[j] = arena[j], arena[i]
+}
+
+func (arena *scheduleArena) Push(value interface{}) {
+	*arena = append(*arena, value.(*task))
+}
+
+func (arena *scheduleArena) Pop() interface{} {
+	old := *arena
+	n := len(old)
+	x := old[n-1]
+	*arena = old[0 : n-1]
+	return x
+}
+
+// AddTask queues function,it is retried until it succeeds,its retry policy gives it up or the queue shuts down
+func (query *retryQueue) AddTask(function func() error, priority uint8, options ...taskOptions) error {
+	return query.AddTaskContext(func(ctx context.Context) error {
+		return function()
+	}, priority, options...)
+}
+
+// AddTaskContext queues function,its context is canceled when Shutdown gives up waiting for the running tasks
+func (query *retryQueue) AddTaskContext(function func(ctx context.Context) error, priority uint8, options ...taskOptions) error {
+	return query.add(query.newTask(function, priority, options))
+}
+
+func (query *retryQueue) newTask(function func(ctx context.Context) error, priority uint8, options []taskOptions) *task {
+	cur := &task{
+		id:       atomic.AddUint64(&query.lastID, 1),
+		exec:     function,
+		priority: priority,
+		policy:   query.defaultPolicy,
+		options:  options,
+	}
+	for i := 0; i < len(options); i++ {
+		options[i](cur)
+	}
+	return cur
+}
+
+// add queues a new task,a durable one is logged first without holding the queue
+func (query *retryQueue) add(cur *task) error {
 	query.check()
+	select {
+	case <-query.closing:
+		return ErrQueueClosed
+	default:
+	}
+	if err := query.logEnqueue(cur); err != nil {
+		return err
+	}
 	query.rw.Lock()
 	defer query.rw.Unlock()
-	task := task{
-		exec:     function,
-		priority: priority,
+	// checked under the lock,so a task is either refused or still in the queue when Shutdown empties it
+	select {
+	case <-query.closing:
+		if cur.handler != "" {
+			// the task is in the log already,the next opening of the log replays it like the other tasks left
+			return nil
+		}
+		return ErrQueueClosed
+	default:
+	}
+	query.pushLocked(cur)
+	return nil
+}
+
+func (query *retryQueue) push(task *task) {
+	query.rw.Lock()
+	defer query.rw.Unlock()
+	query.pushLocked(task)
+}
+
+// AddTaskAt queues function to run at at,or as soon as possible when at is past
+func (query *retryQueue) AddTaskAt(at time.Time, function func(ctx context.Context) error, priority uint8, options ...taskOptions) error {
+	cur := query.newTask(function, priority, options)
+	cur.runAt = at
+	return query.add(cur)
+}
+
+// AddTaskAfter queues function to run once delay is over,e.g. to call a webhook again in 10 minutes
+func (query *retryQueue) AddTaskAfter(delay time.Duration, function func(ctx context.Context) error, priority uint8, options ...taskOptions) error {
+	return query.AddTaskAt(query.clock.Now().Add(delay), function, priority, options...)
+}
+
+// AddRecurringTask queues function to run at every time of schedule,
+// each run is retried with the policy of the task and the next one is planned when it completes or is given up
+func (query *retryQueue) AddRecurringTask(schedule Schedule, function func(ctx context.Context) error, priority uint8, options ...taskOptions) error {
+	next := schedule.Next(query.clock.Now())
+	if next.IsZero() {
+		return ErrScheduleEnded
+	}
+	cur := query.newTask(function, priority, options)
+	cur.runAt, cur.schedule = next, schedule
+	return query.add(cur)
+}
+
+// AddCronTask is AddRecurringTask with the cron expression spec,see ParseCron
+func (query *retryQueue) AddCronTask(spec string, function func(ctx context.Context) error, priority uint8, options ...taskOptions) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	return query.AddRecurringTask(schedule, function, priority, options...)
+}
+
+// reschedule queues a recurring task for its next run,with no attempts
+func (query *retryQueue) reschedule(task *task) {
+	if task.schedule == nil {
+		return
 	}
-	heap.Push(&query.arena, &task)
-	query.loaf = false
+	next := task.schedule.Next(query.clock.Now())
+	if next.IsZero() {
+		return
+	}
+	task.attempts, task.firstRun, task.lastDelay, task.history = 0, time.Time{}, 0, nil
+	task.runAt = next
+	query.push(task)
+}
+
+// pushAfter queues task again once delay is over
+func (query *retryQueue) pushAfter(task *task, delay time.Duration) {
+	task.runAt = query.clock.Now().Add(delay)
+	query.push(task)
 }
 
-func (query *retryQueue) Run() {
-	var baseWaitTime = 1 * time.Millisecond
-	var nowWaitTime = baseWaitTime
-	var times = 1
+// pushLocked puts task in arena when it is due and in waiting otherwise,then wakes Run
+func (query *retryQueue) pushLocked(task *task) {
+	if task.runAt.After(query.clock.Now()) {
+		containerHeap.Push(&query.waiting, task)
+	} else {
+		containerHeap.Push(&query.arena, task)
+	}
+	select {
+	case query.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run hands the due tasks to the idle workers by priority until ctx is done or Shutdown is called,
+// it sleeps until a worker is free,a task is added or the next task is due.
+// when ctx is done the running tasks are aborted and Run returns ctx.Err(),Shutdown still returns what is left
+func (query *retryQueue) Run(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&query.state, queueIdle, queueRunning) {
+		if atomic.LoadInt32(&query.state) == queueClosed {
+			return ErrQueueClosed
+		}
+		return ErrQueueRunning
+	}
+	defer func() {
+		atomic.StoreInt32(&query.state, queueClosed)
+		close(query.stopped)
+	}()
+	idle := make([]int, 0, len(query.work))
+	// armed only while a task waits,a nil channel never fires
+	var due Timer
+	var dueC <-chan time.Time
+	defer func() {
+		if due != nil {
+			due.Stop()
+		}
+	}()
 	for {
+		next, waiting := query.dispatch(&idle)
+		if due != nil {
+			due.Stop()
+			due, dueC = nil, nil
+		}
+		if waiting {
+			due = query.clock.NewTimer(next)
+			dueC = due.C()
+		}
 		select {
-		case <-query.remained:
-			if query.loaf {
-				nowWaitTime = baseWaitTime * (1 << times)
-				if nowWaitTime > query.maxSleepTime {
-					nowWaitTime = query.maxSleepTime
-				}
-				times++
-				query.remained <- struct{}{}
-			} else {
-				nowWaitTime = baseWaitTime
-				times = 1
-			}
-			time.Sleep(nowWaitTime)
+		case <-ctx.Done():
+			query.close()
+			query.cancelTasks()
+			return ctx.Err()
+		case <-query.closing:
+			return nil
 		case workIndex := <-query.signal:
-			query.rw.Lock()
-			var cur *task
-			flag := len(query.arena) <= 0
-			if !flag {
-				cur = query.arena.Pop().(*task)
-			}
-			query.rw.Unlock()
-			if flag {
-				query.loaf = true
-				if len(query.remained) == 0 {
-					query.remained <- struct{}{}
-				}
-				//return the idle work
-				query.signal <- workIndex
-				continue
-			} else {
-				query.work[workIndex].addTask(cur)
-				go query.work[workIndex].work()
-			}
+			idle = append(idle, workIndex)
+		case <-query.wake:
+		case <-dueC:
+		}
+	}
+}
 
+// dispatch moves the tasks now due to arena and hands them to the idle workers,
+// it returns how long until the next waiting task is due,false when none waits
+func (query *retryQueue) dispatch(idle *[]int) (time.Duration, bool) {
+	query.rw.Lock()
+	defer query.rw.Unlock()
+	now := query.clock.Now()
+	for len(query.waiting) > 0 && !query.waiting[0].runAt.After(now) {
+		containerHeap.Push(&query.arena, containerHeap.Pop(&query.waiting))
+	}
+	for len(*idle) > 0 && len(query.arena) > 0 {
+		workIndex := (*idle)[len(*idle)-1]
+		*idle = (*idle)[:len(*idle)-1]
+		query.running.Add(1)
+		query.work[workIndex].addTask(containerHeap.Pop(&query.arena).(*task))
+		go query.work[workIndex].work()
+	}
+	if len(query.waiting) == 0 {
+		return 0, false
+	}
+	return query.waiting[0].runAt.Sub(now), true
+}
+
+func (query *retryQueue) close() {
+	query.closeOnce.Do(func() {
+		close(query.closing)
+	})
+}
+
+// Shutdown stops taking tasks and waits for the running ones to finish their retries,
+// once ctx is done it aborts them through their context,waits for them to return and returns ctx.Err(),
+// so a task ignoring its context keeps Shutdown waiting until it returns.
+// the tasks left in the queue are returned,the due ones by priority and then the waiting ones by run time,
+// they can be added to another queue or stored. the durable ones stay in the log and are replayed by the next OpenDurableRetryQuery
+func (query *retryQueue) Shutdown(ctx context.Context) ([]PendingTask, error) {
+	query.close()
+	if atomic.CompareAndSwapInt32(&query.state, queueIdle, queueClosed) {
+		close(query.stopped)
+	}
+	<-query.stopped
+	idle := make(chan struct{})
+	go func() {
+		query.running.Wait()
+		close(idle)
+	}()
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+		query.cancelTasks()
+		<-idle
+	}
+	query.rw.Lock()
+	defer query.rw.Unlock()
+	pending := make([]PendingTask, 0, len(query.arena)+len(query.waiting))
+	for len(query.arena) > 0 {
+		pending = append(pending, containerHeap.Pop(&query.arena).(*task).pending())
+	}
+	for len(query.waiting) > 0 {
+		pending = append(pending, containerHeap.Pop(&query.waiting).(*task).pending())
+	}
+	if query.wal != nil {
+		if closeErr := query.wal.close(); err == nil {
+			err = closeErr
 		}
 	}
+	return pending, err
 }
 
+func (task *task) pending() PendingTask {
+	return PendingTask{
+		ID:       task.id,
+		Name:     task.name,
+		Exec:     task.exec,
+		Priority: task.priority,
+		Policy:   task.policy,
+		Attempts: task.attempts,
+		RunAt:    task.runAt,
+		Schedule: task.schedule,
+		Handler:  task.handler,
+		Payload:  task.payload,
+	}
+}
@@ -0,0 +1,234 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryQueueRun(t *testing.T) {
+	query := NewRetryQuery(time.Millisecond, WithConcurrencyNumber(2))
+	done := make(chan struct{})
+	var fails int32
+	query.AddTask(func() error {
+		if atomic.AddInt32(&fails, 1) < 3 {
+			return errors.New("not yet")
+		}
+		close(done)
+		return nil
+	}, HighPriority)
+	go query.Run(context.Background())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not retried until it succeeded")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pending, err := query.Shutdown(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("shutdown of an empty queue gives %d tasks,%v", len(pending), err)
+	}
+	if err = query.AddTask(func() error { return nil }, LowPriority); err != ErrQueueClosed {
+		t.Fatalf("task added after shutdown gives %v", err)
+	}
+	if err = query.Run(context.Background()); err != ErrQueueClosed {
+		t.Fatalf("run after shutdown gives %v", err)
+	}
+}
+
+func TestRetryQueueShutdownAborts(t *testing.T) {
+	var failures int32
+	query := NewRetryQuery(time.Hour, WithConcurrencyNumber(1), WithMaxRetryTimesPerTime(5),
+		WithHooks(TaskHooks{OnFailure: func(outcome TaskOutcome) { atomic.AddInt32(&failures, 1) }}))
+	started := make(chan struct{}, 1)
+	aborted := make(chan struct{})
+	query.AddTaskContext(func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		close(aborted)
+		return ctx.Err()
+	}, HighPriority, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	query.AddTask(func() error { return nil }, LowPriority)
+	go query.Run(context.Background())
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	pending, err := query.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("shutdown past its deadline gives %v", err)
+	}
+	<-aborted
+	// the aborted task and the one never started are both handed back,by priority
+	if len(pending) != 2 || pending[0].Priority != HighPriority || pending[1].Priority != LowPriority {
+		t.Fatalf("pending tasks %+v", pending)
+	}
+	// the aborted attempt didn't count,so the task with a single attempt wasn't given up
+	if pending[0].Attempts != 0 || atomic.LoadInt32(&failures) != 0 {
+		t.Fatalf("aborted attempt counted,%d attempts and %d failures", pending[0].Attempts, failures)
+	}
+}
+
+func TestRetryQueueShutdownBeforeRun(t *testing.T) {
+	query := NewRetryQuery(time.Millisecond)
+	query.AddTask(func() error { return nil }, MiddlerPriority)
+	pending, err := query.Shutdown(context.Background())
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("shutdown before run gives %d tasks,%v", len(pending), err)
+	}
+}
+
+func TestRetryQueueRunContext(t *testing.T) {
+	query := NewRetryQuery(time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- query.Run(ctx)
+	}()
+	for atomic.LoadInt32(&query.state) == queueIdle {
+		time.Sleep(time.Millisecond)
+	}
+	if err := query.Run(context.Background()); err != ErrQueueRunning {
+		t.Fatalf("second run gives %v", err)
+	}
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("run gives %v after its context is canceled", err)
+	}
+}
+
+func TestRetryQueuePolicy(t *testing.T) {
+	gaveUp := make(chan struct{}, 2)
+	query := NewRetryQuery(time.Millisecond, WithConcurrencyNumber(2), WithMaxRetryTimesPerTime(2),
+		WithHooks(TaskHooks{OnGiveUp: func(outcome TaskOutcome) { gaveUp <- struct{}{} }}))
+	var limited, permanent int32
+	query.AddTask(func() error {
+		atomic.AddInt32(&limited, 1)
+		return errors.New("down")
+	}, HighPriority, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: ExponentialBackoff(time.Millisecond, 4*time.Millisecond)}))
+	query.AddTask(func() error {
+		atomic.AddInt32(&permanent, 1)
+		return Permanent(errors.New("invalid"))
+	}, HighPriority)
+	go query.Run(context.Background())
+	for i := 0; i < 2; i++ {
+		select {
+		case <-gaveUp:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d of the tasks given up,tasks ran %d and %d times", i, atomic.LoadInt32(&limited), atomic.LoadInt32(&permanent))
+		}
+	}
+	pending, _ := query.Shutdown(context.Background())
+	if atomic.LoadInt32(&limited) != 5 || atomic.LoadInt32(&permanent) != 1 || len(pending) != 0 {
+		t.Fatalf("tasks ran %d and %d times,%d left,want 5,1 and none", limited, permanent, len(pending))
+	}
+}
+
+func TestRetryQueueShutdownReturnsBackoff(t *testing.T) {
+	query := NewRetryQuery(time.Millisecond, WithConcurrencyNumber(1), WithMaxRetryTimesPerTime(1))
+	ran := make(chan struct{}, 1)
+	query.AddTask(func() error {
+		ran <- struct{}{}
+		return errors.New("down")
+	}, MiddlerPriority, WithRetryPolicy(RetryPolicy{Backoff: ConstantBackoff(time.Hour)}))
+	go query.Run(context.Background())
+	<-ran
+	pending, err := query.Shutdown(context.Background())
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("task waiting for its backoff not returned: %+v,%v", pending, err)
+	}
+}
+
+func TestRetryQueueScheduled(t *testing.T) {
+	query := NewRetryQuery(time.Millisecond, WithConcurrencyNumber(1))
+	ran := make(chan string, 3)
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			ran <- name
+			return nil
+		}
+	}
+	start := time.Now()
+	query.AddTaskAfter(60*time.Millisecond, record("later"), HighPriority)
+	query.AddTaskAt(start.Add(30*time.Millisecond), record("low"), LowPriority)
+	query.AddTaskAt(start.Add(30*time.Millisecond), record("high"), HighPriority)
+	query.AddTaskAfter(time.Hour, record("tomorrow"), HighPriority)
+	go query.Run(context.Background())
+	for _, want := range []string{"high", "low", "later"} {
+		if name := <-ran; name != want {
+			t.Fatalf("%s ran,want %s", name, want)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Fatalf("delayed task ran after %v", elapsed)
+	}
+	pending, _ := query.Shutdown(context.Background())
+	if len(pending) != 1 || pending[0].RunAt.Before(start.Add(time.Hour)) {
+		t.Fatalf("pending %+v,want the task due in an hour", pending)
+	}
+}
+
+func TestRetryQueueRecurring(t *testing.T) {
+	ran := make(chan struct{}, 8)
+	record := func(outcome TaskOutcome) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+	}
+	query := NewRetryQuery(time.Millisecond, WithHooks(TaskHooks{OnComplete: record, OnGiveUp: record}))
+	var runs int32
+	if err := query.AddCronTask("61 * * * *", func(ctx context.Context) error { return nil }, LowPriority); err == nil {
+		t.Fatal("bad cron expression accepted")
+	}
+	query.AddRecurringTask(Every(10*time.Millisecond), func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1)%2 == 1 {
+			return Permanent(errors.New("odd run"))
+		}
+		return nil
+	}, MiddlerPriority)
+	go query.Run(context.Background())
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("recurring task ran %d times", i)
+		}
+	}
+	pending, _ := query.Shutdown(context.Background())
+	// a run given up doesn't stop the schedule
+	if n := atomic.LoadInt32(&runs); n < 3 || len(pending) != 1 || pending[0].Schedule == nil || pending[0].Attempts != 0 {
+		t.Fatalf("%d runs,pending %+v", n, pending)
+	}
+}
+
+func TestRetryQueueClock(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clock := NewFakeClock(start)
+	query := NewRetryQuery(time.Hour, WithConcurrencyNumber(1), WithMaxRetryTimesPerTime(1), WithQueueClock(clock))
+	runs := make(chan time.Time, 2)
+	var attempts int32
+	query.AddTask(func() error {
+		runs <- clock.Now()
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("down")
+		}
+		return nil
+	}, HighPriority)
+	go query.Run(context.Background())
+	<-runs
+	// the backoff of an hour waits on the fake clock
+	waitForTimers(clock, 1)
+	clock.Advance(time.Hour)
+	if at := <-runs; !at.Equal(start.Add(time.Hour)) {
+		t.Fatalf("retry at %s,want an hour after %s", at, start)
+	}
+	if pending, err := query.Shutdown(context.Background()); err != nil || len(pending) != 0 {
+		t.Fatalf("shutdown gives %d tasks,%v", len(pending), err)
+	}
+}
@@ -0,0 +1,542 @@
+package golangUtil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWALSegmentSize is the size past which the log starts a new segment when WithWALSegmentSize isn't given
+	DefaultWALSegmentSize = 16 << 20
+	// DefaultWALMaxSegments is the number of segments past which the log is compacted when WithWALMaxSegments isn't given
+	DefaultWALMaxSegments = 4
+)
+
+// kinds of the records of the log
+const (
+	walEnqueue byte = iota + 1
+	walAttempt
+	walDone
+	walDead
+)
+
+// errWALRecord stops the replay of a segment at a torn or corrupted record
+var errWALRecord = errors.New("retry queue log: bad record")
+
+// walEntry is a durable task as the log knows it
+type walEntry struct {
+	id       uint64
+	handler  string
+	name     string
+	payload  []byte
+	priority uint8
+	attempts uint64
+	// unix nanoseconds,0 when unset
+	runAt    int64
+	firstRun int64
+	// set once the task is given up,the entry stays until its dead letter is released
+	dead *walDeadLetter
+}
+
+// walDeadLetter is the dead letter of a given up task
+type walDeadLetter struct {
+	failedAt int64
+	history  []Attempt
+}
+
+// retryWAL is the write-ahead log of a durable retryQueue,a directory of numbered segments.
+// a record is its length,its CRC-32 and its body,so a record torn by a crash ends the replay of its segment.
+// the log keeps the live entries in memory,compacting writes them to a new segment and removes the older ones
+type retryWAL struct {
+	lock        sync.Mutex
+	dir         string
+	file        *os.File
+	segments    []uint64
+	size        int64
+	segmentSize int64
+	maxSegments int
+	sync        bool
+	live        map[uint64]*walEntry
+	// the last error of a record the queue couldn't return to its caller
+	err error
+}
+
+func walSegmentName(number uint64) string {
+	return fmt.Sprintf("wal-%016d.log", number)
+}
+
+// openRetryWAL replays the segments of dir and opens a new segment for the next records
+func openRetryWAL(dir string, segmentSize int64, maxSegments int, sync bool) (*retryWAL, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	wal := &retryWAL{dir: dir, segmentSize: segmentSize, maxSegments: maxSegments, sync: sync, live: make(map[uint64]*walEntry)}
+	for _, name := range names {
+		var number uint64
+		if _, err = fmt.Sscanf(filepath.Base(name), "wal-%d.log", &number); err != nil {
+			continue
+		}
+		if err = wal.replay(name); err != nil {
+			return nil, err
+		}
+		wal.segments = append(wal.segments, number)
+	}
+	if err = wal.roll(); err != nil {
+		return nil, err
+	}
+	if len(wal.segments) > wal.maxSegments {
+		if err = wal.compact(); err != nil {
+			wal.file.Close()
+			return nil, err
+		}
+	}
+	return wal, nil
+}
+
+// replay applies the records of a segment,a torn record and what follows it are cut off
+func (w *retryWAL) replay(name string) error {
+	file, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var good int64
+	for {
+		body, err := readWALRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = w.apply(body)
+		}
+		if err != nil {
+			// the crash happened while the record was written,it was never acknowledged
+			if err = file.Truncate(good); err != nil {
+				return err
+			}
+			return file.Sync()
+		}
+		good += int64(8 + len(body))
+	}
+}
+
+func readWALRecord(reader *bufio.Reader) ([]byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errWALRecord
+	}
+	length := binary.LittleEndian.Uint32(header[:4])
+	if length == 0 || length > 1<<30 {
+		return nil, errWALRecord
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, errWALRecord
+	}
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errWALRecord
+	}
+	return body, nil
+}
+
+// apply updates the live entries with a record
+func (w *retryWAL) apply(body []byte) error {
+	decoder := walDecoder{data: body[1:]}
+	switch body[0] {
+	case walEnqueue:
+		entry := &walEntry{
+			id:       decoder.uvarint(),
+			handler:  string(decoder.bytes()),
+			name:     string(decoder.bytes()),
+			payload:  decoder.bytes(),
+			priority: uint8(decoder.uvarint()),
+			attempts: decoder.uvarint(),
+			runAt:    decoder.varint(),
+			firstRun: decoder.varint(),
+		}
+		if decoder.err == nil {
+			w.live[entry.id] = entry
+		}
+	case walAttempt:
+		id, attempts, runAt, firstRun := decoder.uvarint(), decoder.uvarint(), decoder.varint(), decoder.varint()
+		if entry, ok := w.live[id]; ok && decoder.err == nil {
+			entry.attempts, entry.runAt, entry.firstRun = attempts, runAt, firstRun
+		}
+	case walDone:
+		id := decoder.uvarint()
+		if decoder.err == nil {
+			delete(w.live, id)
+		}
+	case walDead:
+		id, attempts, dead := decoder.uvarint(), decoder.uvarint(), &walDeadLetter{failedAt: decoder.varint()}
+		for count := decoder.uvarint(); count > 0 && decoder.err == nil; count-- {
+			start, duration, message := decoder.varint(), decoder.varint(), string(decoder.bytes())
+			attempt := Attempt{Start: fromWALTime(start), Duration: time.Duration(duration)}
+			if message != "" {
+				attempt.Err = errors.New(message)
+			}
+			dead.history = append(dead.history, attempt)
+		}
+		if decoder.err == nil && len(dead.history) == 0 {
+			decoder.err = errWALRecord
+		}
+		if entry, ok := w.live[id]; ok && decoder.err == nil {
+			entry.attempts, entry.dead = attempts, dead
+		}
+	default:
+		return errWALRecord
+	}
+	return decoder.err
+}
+
+// entries returns the live entries by id
+func (w *retryWAL) entries() []*walEntry {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	result := make([]*walEntry, 0, len(w.live))
+	for _, entry := range w.live {
+		copied := *entry
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].id < result[j].id
+	})
+	return result
+}
+
+func encodeWALEnqueue(entry *walEntry) []byte {
+	body := []byte{walEnqueue}
+	body = appendUvarint(body, entry.id)
+	body = appendWALBytes(body, []byte(entry.handler))
+	body = appendWALBytes(body, []byte(entry.name))
+	body = appendWALBytes(body, entry.payload)
+	body = appendUvarint(body, uint64(entry.priority))
+	body = appendUvarint(body, entry.attempts)
+	body = appendVarint(body, entry.runAt)
+	return appendVarint(body, entry.firstRun)
+}
+
+func encodeWALDead(id, attempts uint64, dead *walDeadLetter) []byte {
+	body := appendUvarint([]byte{walDead}, id)
+	body = appendUvarint(body, attempts)
+	body = appendVarint(body, dead.failedAt)
+	body = appendUvarint(body, uint64(len(dead.history)))
+	for _, attempt := range dead.history {
+		body = appendVarint(body, walTime(attempt.Start))
+		body = appendVarint(body, int64(attempt.Duration))
+		var message string
+		if attempt.Err != nil {
+			message = attempt.Err.Error()
+		}
+		body = appendWALBytes(body, []byte(message))
+	}
+	return body
+}
+
+func appendUvarint(body []byte, value uint64) []byte {
+	var buffer [binary.MaxVarintLen64]byte
+	return append(body, buffer[:binary.PutUvarint(buffer[:], value)]...)
+}
+
+func appendVarint(body []byte, value int64) []byte {
+	var buffer [binary.MaxVarintLen64]byte
+	return append(body, buffer[:binary.PutVarint(buffer[:], value)]...)
+}
+
+func appendWALBytes(body, value []byte) []byte {
+	body = appendUvarint(body, uint64(len(value)))
+	return append(body, value...)
+}
+
+// enqueue logs a new task
+func (w *retryWAL) enqueue(entry *walEntry) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.append(encodeWALEnqueue(entry)); err != nil {
+		return err
+	}
+	copied := *entry
+	w.live[entry.id] = &copied
+	return w.rollIfFull()
+}
+
+// attempt logs a failed attempt of task id and when it runs again
+func (w *retryWAL) attempt(id, attempts uint64, runAt, firstRun time.Time) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	body := appendUvarint([]byte{walAttempt}, id)
+	body = appendUvarint(body, attempts)
+	body = appendVarint(body, walTime(runAt))
+	body = appendVarint(body, walTime(firstRun))
+	if err := w.append(body); err != nil {
+		return err
+	}
+	if entry, ok := w.live[id]; ok {
+		entry.attempts, entry.runAt, entry.firstRun = attempts, walTime(runAt), walTime(firstRun)
+	}
+	return w.rollIfFull()
+}
+
+// done logs that task id completed or was given up
+func (w *retryWAL) done(id uint64) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.append(appendUvarint([]byte{walDone}, id)); err != nil {
+		return err
+	}
+	delete(w.live, id)
+	return w.rollIfFull()
+}
+
+// dead logs that task id was given up,it stays in the log with its history until release
+func (w *retryWAL) dead(id, attempts uint64, failedAt time.Time, history []Attempt) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	dead := &walDeadLetter{failedAt: walTime(failedAt), history: append([]Attempt(nil), history...)}
+	if err := w.append(encodeWALDead(id, attempts, dead)); err != nil {
+		return err
+	}
+	if entry, ok := w.live[id]; ok {
+		entry.attempts, entry.dead = attempts, dead
+	}
+	return w.rollIfFull()
+}
+
+// release logs that the dead letter of task id is gone,it does nothing unless the log holds it for handler
+func (w *retryWAL) release(id uint64, handler string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if entry, ok := w.live[id]; !ok || entry.dead == nil || entry.handler != handler {
+		return nil
+	}
+	if err := w.append(appendUvarint([]byte{walDone}, id)); err != nil {
+		return err
+	}
+	delete(w.live, id)
+	return w.rollIfFull()
+}
+
+func walTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromWALTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// append writes a record to the current segment and syncs it
+func (w *retryWAL) append(body []byte) error {
+	if w.file == nil {
+		return ErrQueueClosed
+	}
+	if err := w.write(body); err != nil {
+		return err
+	}
+	if w.sync {
+		return w.file.Sync()
+	}
+	return nil
+}
+
+// write frames body as a record at the end of the current segment
+func (w *retryWAL) write(body []byte) error {
+	record := make([]byte, 8, 8+len(body))
+	binary.LittleEndian.PutUint32(record[:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(body))
+	record = append(record, body...)
+	if _, err := w.file.Write(record); err != nil {
+		return err
+	}
+	w.size += int64(len(record))
+	return nil
+}
+
+// rollIfFull starts a new segment once the current one is full,and compacts when there are too many.
+// it is called once the live entries include the last record,which compacting writes out
+func (w *retryWAL) rollIfFull() error {
+	if w.size < w.segmentSize {
+		return nil
+	}
+	if err := w.roll(); err != nil {
+		return err
+	}
+	if len(w.segments) > w.maxSegments {
+		return w.compact()
+	}
+	return nil
+}
+
+// roll closes the current segment and opens the next one
+func (w *retryWAL) roll() error {
+	var number uint64 = 1
+	if len(w.segments) > 0 {
+		number = w.segments[len(w.segments)-1] + 1
+	}
+	file, err := os.OpenFile(filepath.Join(w.dir, walSegmentName(number)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err = w.syncDir(); err != nil {
+		file.Close()
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, w.size = file, 0
+	w.segments = append(w.segments, number)
+	return nil
+}
+
+// compact writes the live entries to a new segment and removes the older segments,
+// a crash in between leaves both and the replay gives the same entries
+func (w *retryWAL) compact() error {
+	if err := w.roll(); err != nil {
+		return err
+	}
+	for _, entry := range w.live {
+		if err := w.write(encodeWALEnqueue(entry)); err != nil {
+			return err
+		}
+		if entry.dead != nil {
+			if err := w.write(encodeWALDead(entry.id, entry.attempts, entry.dead)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	current := w.segments[len(w.segments)-1]
+	for _, number := range w.segments[:len(w.segments)-1] {
+		if err := os.Remove(filepath.Join(w.dir, walSegmentName(number))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	w.segments = []uint64{current}
+	return w.syncDir()
+}
+
+// compactNow compacts the log whatever its number of segments
+func (w *retryWAL) compactNow() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		return ErrQueueClosed
+	}
+	return w.compact()
+}
+
+// syncDir makes the creation and removal of segments durable
+func (w *retryWAL) syncDir() error {
+	if !w.sync {
+		return nil
+	}
+	dir, err := os.Open(w.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if err = dir.Sync(); err != nil && !strings.Contains(err.Error(), "invalid argument") {
+		return err
+	}
+	return nil
+}
+
+// fail keeps err for lastErr,nil is ignored
+func (w *retryWAL) fail(err error) {
+	if err == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.err = err
+}
+
+func (w *retryWAL) lastErr() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+func (w *retryWAL) close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// walDecoder reads the fields of a record,the first error sticks
+type walDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *walDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = errWALRecord
+		return 0
+	}
+	d.data = d.data[n:]
+	return value
+}
+
+func (d *walDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = errWALRecord
+		return 0
+	}
+	d.data = d.data[n:]
+	return value
+}
+
+func (d *walDecoder) bytes() []byte {
+	length := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if length > uint64(len(d.data)) {
+		d.err = errWALRecord
+		return nil
+	}
+	value := append([]byte(nil), d.data[:length]...)
+	d.data = d.data[length:]
+	return value
+}
@@ -0,0 +1,149 @@
+package golangUtil
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucketLimiter refills a bucket of burst tokens at a steady rate,every event takes one token.
+// a full bucket lets a burst through at once,which suits clients that are idle and then busy
+type tokenBucketLimiter struct {
+	lock sync.Mutex
+	// tokens added per second
+	rate  float64
+	burst int64
+	// negative while reservations are waiting for tokens
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+// bucketSettings are the settings the token bucket and GCRA limiters share
+type bucketSettings struct {
+	clock Clock
+}
+
+type bucketOptions func(settings *bucketSettings)
+
+// WithBucketClock sets the clock a token bucket or GCRA limiter reads
+func WithBucketClock(clock Clock) bucketOptions {
+	return func(settings *bucketSettings) {
+		settings.clock = clock
+	}
+}
+
+func newBucketSettings(options []bucketOptions) bucketSettings {
+	settings := bucketSettings{clock: SystemClock}
+	for i := 0; i < len(options); i++ {
+		options[i](&settings)
+	}
+	return settings
+}
+
+// NewTokenBucketLimiter returns a token bucket admitting ratePerSecond events per second on average and up to burst at once,
+// it starts full
+func NewTokenBucketLimiter(ratePerSecond float64, burst int64, options ...bucketOptions) *tokenBucketLimiter {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("token bucket needs a positive rate and burst"))
+	}
+	settings := newBucketSettings(options)
+	return &tokenBucketLimiter{
+		rate:   ratePerSecond,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   settings.clock.Now(),
+		clock:  settings.clock,
+	}
+}
+
+// advance adds the tokens earned since the last call
+func (l *tokenBucketLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(float64(l.burst), l.tokens+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+}
+
+func (l *tokenBucketLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+func (l *tokenBucketLimiter) AllowN(n int64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.advance(l.clock.Now())
+	if l.tokens < float64(n) {
+		return false
+	}
+	l.tokens -= float64(n)
+	return true
+}
+
+func (l *tokenBucketLimiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN takes n tokens now,the bucket goes into debt when it holds fewer and the reservation waits for the refill
+func (l *tokenBucketLimiter) ReserveN(n int64) *Reservation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if n > l.burst {
+		return &Reservation{}
+	}
+	now := l.clock.Now()
+	l.advance(now)
+	l.tokens -= float64(n)
+	timeToAct := now
+	if l.tokens < 0 {
+		timeToAct = now.Add(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+	return &Reservation{ok: true, timeToAct: timeToAct, clock: l.clock, refund: func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.advance(l.clock.Now())
+		l.tokens = math.Min(float64(l.burst), l.tokens+float64(n))
+	}}
+}
+
+// SetLimit changes the rate and the burst of the running bucket,the tokens it holds are kept up to the new burst
+func (l *tokenBucketLimiter) SetLimit(ratePerSecond float64, burst int64) {
+	if ratePerSecond <= 0 || burst < 1 {
+		panic(any("token bucket needs a positive rate and burst"))
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.advance(l.clock.Now())
+	l.rate, l.burst = ratePerSecond, burst
+	l.tokens = math.Min(float64(burst), l.tokens)
+}
+
+// State returns the quota of the bucket,Reset is when it is full again
+func (l *tokenBucketLimiter) State() RateLimitState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.advance(l.clock.Now())
+	state := RateLimitState{
+		Limit: l.burst,
+		Reset: time.Duration((float64(l.burst) - l.tokens) / l.rate * float64(time.Second)),
+	}
+	if l.tokens >= 1 {
+		state.Remaining = int64(l.tokens)
+	} else {
+		state.RetryAfter = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	return state
+}
+
+func (l *tokenBucketLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n tokens are available
+func (l *tokenBucketLimiter) WaitN(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return waitReservation(ctx, l.ReserveN(n))
+}
@@ -0,0 +1,152 @@
+package golangUtil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	limiter := NewTokenBucketLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("event %d of the burst rejected", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("event past the burst admitted")
+	}
+	if limiter.AllowN(4) {
+		t.Fatal("AllowN admitted more than the burst")
+	}
+}
+
+func TestTokenBucketAllowNAllOrNothing(t *testing.T) {
+	limiter := NewTokenBucketLimiter(1, 5)
+	if !limiter.AllowN(3) {
+		t.Fatal("AllowN(3) rejected with 5 tokens")
+	}
+	if limiter.AllowN(3) {
+		t.Fatal("AllowN(3) admitted with 2 tokens")
+	}
+	if !limiter.AllowN(2) {
+		t.Fatal("a rejected AllowN took tokens")
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewTokenBucketLimiter(2, 4, WithBucketClock(clock))
+	limiter.AllowN(4)
+	clock.Advance(499 * time.Millisecond)
+	if limiter.Allow() {
+		t.Fatal("token refilled early")
+	}
+	clock.Advance(time.Millisecond)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("500ms at 2/s didn't refill exactly one token")
+	}
+	// the bucket never holds more than the burst
+	clock.Advance(time.Hour)
+	if !limiter.AllowN(4) || limiter.Allow() {
+		t.Fatal("bucket refilled past the burst")
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewTokenBucketLimiter(10, 1, WithBucketClock(clock))
+	if r := limiter.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("first reservation = %v,%v,want OK now", r.OK(), r.Delay())
+	}
+	r := limiter.Reserve()
+	if delay := r.Delay(); !r.OK() || delay != 100*time.Millisecond {
+		t.Fatalf("second reservation waits %v,want 100ms", delay)
+	}
+	if delay := limiter.Reserve().Delay(); delay != 200*time.Millisecond {
+		t.Fatalf("third reservation waits %v,want 200ms", delay)
+	}
+	r.Cancel()
+	if delay := limiter.Reserve().Delay(); delay != 200*time.Millisecond {
+		t.Fatalf("reservation after a cancel waits %v,want 200ms", delay)
+	}
+	clock.Advance(100 * time.Millisecond)
+	if r.Delay() != 0 {
+		t.Fatal("reservation not due after its delay")
+	}
+	if r := limiter.ReserveN(2); r.OK() {
+		t.Fatal("reservation past the burst is OK")
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewTokenBucketLimiter(1, 1, WithBucketClock(clock))
+	if err := limiter.Wait(context.Background()); err != nil || clock.Timers() != 0 {
+		t.Fatalf("Wait with a token = %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(context.Background())
+	}()
+	waitForTimers(clock, 1)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, ErrExceedsDeadline) {
+		t.Fatalf("Wait past the deadline = %v", err)
+	}
+	// the canceled reservation gave its token back
+	if delay := limiter.Reserve().Delay(); delay != time.Second {
+		t.Fatalf("reservation after a cancel waits %v", delay)
+	}
+
+	canceled, stop := context.WithCancel(context.Background())
+	stop()
+	if err := limiter.Wait(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait on a canceled context = %v", err)
+	}
+	if err := limiter.WaitN(context.Background(), 2); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("WaitN past the burst = %v", err)
+	}
+}
+
+func TestRegistryLimiter(t *testing.T) {
+	for _, limiter := range []Limiter{NewTokenBucketLimiter(1, 2), NewGCRALimiter(1, 2)} {
+		handler := RegistryLimiter(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		var codes []int
+		for i := 0; i < 3; i++ {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			codes = append(codes, recorder.Code)
+		}
+		if codes[0] != 200 || codes[1] != 200 || codes[2] != 429 {
+			t.Fatalf("%T: codes = %v", limiter, codes)
+		}
+	}
+}
+
+func TestTokenBucketSetLimit(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewTokenBucketLimiter(1, 4, WithBucketClock(clock))
+	limiter.AllowN(3)
+	limiter.SetLimit(10, 2)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("tokens not kept across SetLimit")
+	}
+	clock.Advance(100 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("new rate not used")
+	}
+	clock.Advance(time.Hour)
+	if !limiter.AllowN(2) || limiter.Allow() {
+		t.Fatal("new burst not used")
+	}
+}